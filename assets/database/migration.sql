@@ -0,0 +1,125 @@
+-- Upgrade of a database of the first version (webhook_config and webhook_transaction without dispatcher columns).
+-- Every statement is idempotent, it can run again.
+
+BEGIN;
+
+ALTER TABLE public.webhook_config
+    ADD COLUMN IF NOT EXISTS retry_max_attempts  int NULL,
+    ADD COLUMN IF NOT EXISTS retry_base_delay_ms int NULL,
+    ADD COLUMN IF NOT EXISTS retry_max_delay_ms  int NULL,
+    -- the existing endpoints were sent unsigned, they keep it explicitly until a secret is set
+    ADD COLUMN IF NOT EXISTS signature_mode      varchar(20) NOT NULL DEFAULT 'NONE',
+    ADD COLUMN IF NOT EXISTS signing_secret      varchar(200) NULL,
+    ADD COLUMN IF NOT EXISTS signing_secret_previous             varchar(200) NULL,
+    ADD COLUMN IF NOT EXISTS signing_secret_previous_expires_at  timestamptz NULL,
+    ADD COLUMN IF NOT EXISTS response_rules      jsonb NULL,
+    ADD COLUMN IF NOT EXISTS enabled             boolean NOT NULL DEFAULT true,
+    ADD COLUMN IF NOT EXISTS disabled_reason     text NULL,
+    ADD COLUMN IF NOT EXISTS rate_limit_rps      double precision NULL,
+    ADD COLUMN IF NOT EXISTS rate_limit_burst    int NULL,
+    ADD COLUMN IF NOT EXISTS max_in_flight       int NULL,
+    ADD COLUMN IF NOT EXISTS weight              int NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS ordering_mode       varchar(20) NOT NULL DEFAULT 'NONE';
+
+-- the new endpoints are signed by default
+ALTER TABLE public.webhook_config ALTER COLUMN signature_mode SET DEFAULT 'HMAC';
+
+ALTER TABLE public.webhook_transaction
+    ADD COLUMN IF NOT EXISTS webhook_config_id   int NULL REFERENCES public.webhook_config(id),
+    ADD COLUMN IF NOT EXISTS claimed_by          varchar(200) NULL,
+    ADD COLUMN IF NOT EXISTS lease_expires_at    timestamptz NULL,
+    ADD COLUMN IF NOT EXISTS attempts            int NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS next_attempt_at     timestamptz NULL,
+    ADD COLUMN IF NOT EXISTS last_error          text NULL,
+    ADD COLUMN IF NOT EXISTS error_class         varchar(50) NULL,
+    ADD COLUMN IF NOT EXISTS ordering_key        varchar(300) NULL,
+    ADD COLUMN IF NOT EXISTS sequence_no         bigint NULL,
+    ADD COLUMN IF NOT EXISTS kafka_topic         varchar(200) NULL,
+    ADD COLUMN IF NOT EXISTS kafka_partition     int NULL,
+    ADD COLUMN IF NOT EXISTS kafka_offset        bigint NULL,
+    ADD COLUMN IF NOT EXISTS kafka_key           varchar(200) NULL,
+    ADD COLUMN IF NOT EXISTS event_id            varchar(300) NULL,
+    ADD COLUMN IF NOT EXISTS replayed_by         varchar(200) NULL,
+    ADD COLUMN IF NOT EXISTS replayed_at         timestamptz NULL;
+
+-- the pending webhooks of the first version get the webhook_config they were created from
+UPDATE public.webhook_transaction t
+SET webhook_config_id = (	SELECT min(c.id)
+							FROM public.webhook_config c
+							WHERE c.receiver = t.receiver
+							and c.url = t.url
+							and c.method = t.method)
+WHERE t.status = 'IN-QUEUE:WAITING-FOR-SEND'
+and t.webhook_config_id is null;
+
+-- the dispatcher only claims the webhooks with a next attempt, the pending webhooks are due now
+UPDATE public.webhook_transaction
+SET next_attempt_at = now()
+WHERE status = 'IN-QUEUE:WAITING-FOR-SEND'
+and next_attempt_at is null
+and webhook_config_id is not null;
+
+-- a pending webhook whose webhook_config no longer exists is never claimed
+UPDATE public.webhook_transaction
+SET status = 'IN-QUEUE:MSG-DISCARDED-NO-WEBHOOK-SETUP'
+WHERE status = 'IN-QUEUE:WAITING-FOR-SEND'
+and webhook_config_id is null;
+
+CREATE TABLE IF NOT EXISTS public.webhook_ordering_sequence (
+    ordering_key        varchar(300) PRIMARY KEY,
+    last_sequence_no    bigint NOT NULL,
+    updated_at          timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS public.webhook_signing_key (
+    kid             varchar(100) PRIMARY KEY,
+    algorithm       varchar(20) NOT NULL,
+    private_key     bytea NOT NULL,
+    key_encrypted   boolean NOT NULL DEFAULT false,
+    public_key      bytea NOT NULL,
+    created_at      timestamptz NOT NULL DEFAULT now(),
+    active_at       timestamptz NOT NULL DEFAULT now(),
+    retired_at      timestamptz NULL
+);
+
+-- the private keys created from now on are encrypted, the existing ones are retired by a rotation
+ALTER TABLE public.webhook_signing_key
+    ADD COLUMN IF NOT EXISTS key_encrypted   boolean NOT NULL DEFAULT false;
+
+-- a rotated key is published before it signs (active_at), the existing keys are active since their creation
+ALTER TABLE public.webhook_signing_key
+    ADD COLUMN IF NOT EXISTS active_at       timestamptz NULL;
+
+UPDATE public.webhook_signing_key
+SET active_at = created_at
+WHERE active_at is null;
+
+ALTER TABLE public.webhook_signing_key
+    ALTER COLUMN active_at SET DEFAULT now(),
+    ALTER COLUMN active_at SET NOT NULL;
+
+CREATE TABLE IF NOT EXISTS public.webhook_delivery_attempt (
+    id                      serial PRIMARY KEY,
+    webhook_transaction_id  int NOT NULL REFERENCES public.webhook_transaction(id),
+    attempt                 int NOT NULL,
+    request_headers         jsonb NULL,
+    response_status         int NOT NULL,
+    response_body           text NOT NULL DEFAULT '',
+    latency_ms              bigint NOT NULL,
+    error_class             varchar(50) NOT NULL DEFAULT '',
+    error                   text NOT NULL DEFAULT '',
+    pod_name                varchar(200) NOT NULL,
+    created_at              timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_attempt_transaction_idx ON public.webhook_delivery_attempt (webhook_transaction_id);
+
+CREATE INDEX IF NOT EXISTS webhook_transaction_status_idx ON public.webhook_transaction (status, created_at);
+CREATE INDEX IF NOT EXISTS webhook_transaction_error_class_idx ON public.webhook_transaction (error_class) WHERE error_class IS NOT NULL;
+CREATE INDEX IF NOT EXISTS webhook_transaction_next_attempt_idx ON public.webhook_transaction (next_attempt_at) WHERE next_attempt_at IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS webhook_transaction_event_idx ON public.webhook_transaction (event_id, receiver, (COALESCE(webhook_config_id, 0))) WHERE event_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS webhook_transaction_ordering_idx ON public.webhook_transaction (ordering_key, sequence_no) WHERE next_attempt_at IS NOT NULL;
+DROP INDEX IF EXISTS webhook_transaction_receiver_next_attempt_idx;
+CREATE INDEX IF NOT EXISTS webhook_transaction_config_next_attempt_idx ON public.webhook_transaction (webhook_config_id, next_attempt_at) WHERE next_attempt_at IS NOT NULL;
+
+COMMIT;
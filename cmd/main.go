@@ -7,6 +7,7 @@ import(
 	"syscall"
 	"context"
 	"sync"
+	"errors"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -14,11 +15,14 @@ import(
 	"github.com/go-worker-webhook/internal/infra/configuration"
 	"github.com/go-worker-webhook/internal/core/model"
 	"github.com/go-worker-webhook/internal/core/service"
+	"github.com/go-worker-webhook/internal/core/handler"
 	"github.com/go-worker-webhook/internal/adapter/database"
 	"github.com/go-worker-webhook/internal/adapter/event"
+	"github.com/go-worker-webhook/internal/adapter/api"
+	"github.com/go-worker-webhook/internal/adapter/client"
 	"github.com/go-worker-webhook/internal/infra/server"
+	"github.com/go-worker-webhook/internal/infra/dispatcher"
 
-	go_core_api "github.com/eliezerraj/go-core/api"
 	go_core_pg "github.com/eliezerraj/go-core/database/pg"  
 )
 
@@ -40,12 +44,30 @@ func init(){
 	configOTEL 		:= configuration.GetOtelEnv()
 	databaseConfig 	:= configuration.GetDatabaseEnv() 
 	kafkaConfigurations, topics := configuration.GetKafkaEnv() 
+	dispatcherConfig := configuration.GetDispatcherEnv()
+	retryPolicy := configuration.GetRetryEnv()
+	httpServerConfig := configuration.GetHttpServerEnv()
+	signingConfig := configuration.GetSigningEnv()
+	breakerConfig := configuration.GetBreakerEnv()
+	pixConfig := configuration.GetPixEnv()
+	topicRouting := configuration.GetTopicRoutingEnv()
+	consumerConfig := configuration.GetConsumerEnv()
+	backpressureConfig := configuration.GetBackpressureEnv()
 
 	appServer.InfoPod = &infoPod
 	appServer.ConfigOTEL = &configOTEL
 	appServer.DatabaseConfig = &databaseConfig
 	appServer.KafkaConfigurations = &kafkaConfigurations
 	appServer.Topics = topics
+	appServer.TopicRouting = &topicRouting
+	appServer.ConsumerConfig = &consumerConfig
+	appServer.BackpressureConfig = &backpressureConfig
+	appServer.DispatcherConfig = &dispatcherConfig
+	appServer.RetryPolicy = &retryPolicy
+	appServer.Server = &httpServerConfig
+	appServer.SigningConfig = &signingConfig
+	appServer.BreakerConfig = &breakerConfig
+	appServer.PixConfig = &pixConfig
 }
 
 func main()  {
@@ -78,13 +100,35 @@ func main()  {
 	// Database
 	database := database.NewWorkerRepository(&databasePGServer)
 
-	// Create a go-core api service for client http
-	coreRestApiService := go_core_api.NewRestApiService()
-	workerService := service.NewWorkerService(*coreRestApiService, database)
+	// Create a http client to call the receivers
+	webHookClient := client.NewWebHookClient(10 * time.Second)
+
+	// Register the handlers of the event types
+	eventRegistry := handler.NewRegistry(handler.NewPixHandler(appServer.PixConfig))
+	for topic, eventType := range appServer.TopicRouting.Routes {
+		if _, ok := eventRegistry.Get(eventType); !ok {
+			childLogger.Warn().Str("topic", topic).Str("type", eventType).Msg("topic routed to a type without handler")
+		}
+	}
+
+	workerService := service.NewWorkerService(webHookClient, database, appServer.RetryPolicy, appServer.SigningConfig, appServer.BreakerConfig, eventRegistry)
+
+	// Create the first signing key (asymmetric signature)
+	err = workerService.EnsureSigningKey(ctx)
+	if err != nil {
+		childLogger.Error().Err(err).Msg("error create signing key")
+	}
 	
 	// Kafka
+	// without dead-letter topic a poison message would hold its partition, skipping it must be explicit
+	if appServer.ConsumerConfig.DlqTopic == "" && !appServer.ConsumerConfig.DlqSkip {
+		err = errors.New("KAFKA_TOPIC_DLQ not set, set KAFKA_DLQ_SKIP=true to skip the messages instead")
+		childLogger.Error().Err(err).Msg("fatal error consumer config aborting")
+		panic(err)
+	}
 	workerEvent, err := event.NewWorkerEvent(ctx, 
 											appServer.Topics, 
+											appServer.ConsumerConfig.DlqTopic,
 											appServer.KafkaConfigurations)
 	if err != nil {
 		childLogger.Error().Err(err).Msg("error open kafka")
@@ -92,15 +136,23 @@ func main()  {
 	}
 
 	serverWorker := server.NewServerWorker(workerService, workerEvent)
+	webhookDispatcher := dispatcher.NewDispatcher(workerService, appServer.DispatcherConfig)
 
-	var wg, wg_webhook sync.WaitGroup
+	httpRouters := api.NewHttpRouters(workerService)
+	httpServer := server.NewHttpAppServer(appServer.Server)
+
+	var wg, wg_webhook, wg_http sync.WaitGroup
 
 	wg.Add(1)
 	go serverWorker.Consumer(ctx, &appServer, &wg)
 
 	wg_webhook.Add(1)
-	go serverWorker.SendWebhook(ctx, &appServer, &wg_webhook)
+	go webhookDispatcher.Start(ctx, &appServer, &wg_webhook)
+
+	wg_http.Add(1)
+	go httpServer.StartHttpAppServer(ctx, &httpRouters, &appServer, &wg_http)
 	
 	wg.Wait()
 	wg_webhook.Wait()
+	wg_http.Wait()
 }
\ No newline at end of file
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/go-worker-webhook/internal/core/service"
+	"github.com/go-worker-webhook/internal/core/model"
+	"github.com/go-worker-webhook/internal/core/erro"
+	go_core_observ "github.com/eliezerraj/go-core/observability"
+)
+
+var childLogger = log.With().Str("component","go-worker-webhook").Str("package","internal.adapter.api").Logger()
+var tracerProvider go_core_observ.TracerProvider
+
+type HttpRouters struct {
+	workerService 	*service.WorkerService
+}
+
+type MessageBody struct {
+	ErrorMsg 	*string `json:"error,omitempty"`
+	Msg 		*string `json:"message,omitempty"`
+}
+
+// About create a http routers
+func NewHttpRouters(workerService *service.WorkerService) HttpRouters {
+	childLogger.Info().Str("func","NewHttpRouters").Send()
+
+	return HttpRouters{
+		workerService: workerService,
+	}
+}
+
+// About write a json response
+func writeJSON(w http.ResponseWriter, code int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(data)
+}
+
+// About convert an error to http status code
+func writeError(w http.ResponseWriter, err error) {
+	var code int
+	switch {
+	case errors.Is(err, erro.ErrInvalid), errors.Is(err, erro.ErrUnmarshal):
+		code = http.StatusBadRequest
+	case errors.Is(err, erro.ErrNotFound):
+		code = http.StatusNotFound
+	default:
+		code = http.StatusInternalServerError
+	}
+	msg := err.Error()
+	writeJSON(w, code, MessageBody{ErrorMsg: &msg})
+}
+
+// About return a health
+func (h *HttpRouters) Health(rw http.ResponseWriter, req *http.Request) {
+	childLogger.Debug().Str("func","Health").Send()
+
+	writeJSON(rw, http.StatusOK, map[string]bool{"status": true})
+}
+
+// About replay the dead-letter webhooks
+func (h *HttpRouters) ReplayWebHooks(rw http.ResponseWriter, req *http.Request) {
+	childLogger.Info().Str("func","ReplayWebHooks").Interface("trace-resquest-id", req.Context().Value("trace-request-id")).Send()
+
+	//trace
+	span := tracerProvider.Span(req.Context(), "adapter.api.ReplayWebHooks")
+	defer span.End()
+
+	replayFilter := model.ReplayFilter{}
+	err := json.NewDecoder(req.Body).Decode(&replayFilter)
+	if err != nil {
+		writeError(rw, erro.ErrUnmarshal)
+		return
+	}
+	defer req.Body.Close()
+
+	// the actor is the authenticated identity, never the request body
+	replayFilter.ReplayedBy = fmt.Sprintf("%v", req.Context().Value("admin-identity"))
+
+	res, err := h.workerService.ReplayWebHooks(req.Context(), &replayFilter)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, map[string]int64{"replayed": res})
+}
+
+// About get the public keys of the asymmetric signature
+func (h *HttpRouters) GetJWKS(rw http.ResponseWriter, req *http.Request) {
+	childLogger.Debug().Str("func","GetJWKS").Send()
+
+	res, err := h.workerService.GetJWKS(req.Context())
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+
+	rw.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%v", h.workerService.JWKSMaxAge()))
+	writeJSON(rw, http.StatusOK, res)
+}
+
+// About rotate the signing key of the asymmetric signature
+func (h *HttpRouters) RotateSigningKey(rw http.ResponseWriter, req *http.Request) {
+	childLogger.Info().Str("func","RotateSigningKey").Interface("trace-resquest-id", req.Context().Value("trace-request-id")).Send()
+
+	//trace
+	span := tracerProvider.Span(req.Context(), "adapter.api.RotateSigningKey")
+	defer span.End()
+
+	res, err := h.workerService.RotateSigningKey(req.Context())
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, res)
+}
+
+// About list the delivery attempts of a webhook
+func (h *HttpRouters) ListDeliveryAttempts(rw http.ResponseWriter, req *http.Request) {
+	childLogger.Info().Str("func","ListDeliveryAttempts").Interface("trace-resquest-id", req.Context().Value("trace-request-id")).Send()
+
+	//trace
+	span := tracerProvider.Span(req.Context(), "adapter.api.ListDeliveryAttempts")
+	defer span.End()
+
+	id, err := strconv.Atoi(req.PathValue("id"))
+	if err != nil {
+		writeError(rw, erro.ErrInvalid)
+		return
+	}
+
+	res, err := h.workerService.ListDeliveryAttempts(req.Context(), id)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, res)
+}
+
+// About list the circuit breakers of the receiver hosts
+func (h *HttpRouters) GetCircuitBreakers(rw http.ResponseWriter, req *http.Request) {
+	childLogger.Debug().Str("func","GetCircuitBreakers").Send()
+
+	res := h.workerService.GetCircuitBreakers(req.Context())
+
+	writeJSON(rw, http.StatusOK, res)
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
+	go_core_observ "github.com/eliezerraj/go-core/observability"
+)
+
+var childLogger = log.With().Str("component","go-worker-webhook").Str("package","internal.adapter.client").Logger()
+var tracerProvider go_core_observ.TracerProvider
+
+// max bytes of the response body kept
+const responseBodyLimit = 4096
+
+type WebHookClient struct {
+	client 	*http.Client
+}
+
+type WebHookResponse struct {
+	StatusCode	int
+	Header		http.Header
+	Body		string
+	Latency		time.Duration
+}
+
+// About create a http client to call the receivers
+func NewWebHookClient(timeout time.Duration) *WebHookClient {
+	childLogger.Info().Str("func","NewWebHookClient").Send()
+
+	return &WebHookClient{
+		client: &http.Client{ Timeout: timeout },
+	}
+}
+
+// About call the receiver with the exact body (the signature is over these bytes)
+// The status code is 0 when there is no response (timeout, connection refused ...)
+func (c *WebHookClient) Send(ctx context.Context, method string, url string, headers map[string]string, body []byte) (*WebHookResponse, error) {
+	childLogger.Info().Str("func","Send").Str("url", url).Send()
+
+	//trace
+	span := tracerProvider.Span(ctx, "adapter.client.Send")
+	defer span.End()
+
+	webHookResponse := WebHookResponse{}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return &webHookResponse, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	webHookResponse.Latency = time.Since(start)
+	if err != nil {
+		return &webHookResponse, err
+	}
+	defer resp.Body.Close()
+
+	// keep the begin of the body and drain the rest to reuse the connection
+	res_body, _ := io.ReadAll(io.LimitReader(resp.Body, responseBodyLimit))
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64 * 1024))
+
+	webHookResponse.StatusCode = resp.StatusCode
+	webHookResponse.Header = resp.Header
+	webHookResponse.Body = textOf(res_body)
+
+	return &webHookResponse, nil
+}
+
+// About the response body as text, the column rejects invalid utf-8 and NUL (the attempt would not be recorded)
+// The rune cut by the limit is dropped, other invalid bytes are replaced
+func textOf(body []byte) string {
+	start := len(body) - 1
+	for start > 0 && len(body) - start < utf8.UTFMax && !utf8.RuneStart(body[start]) {
+		start--
+	}
+	if start >= 0 && !utf8.FullRune(body[start:]) {
+		body = body[:start]
+	}
+
+	return strings.ReplaceAll(strings.ToValidUTF8(string(body), "\uFFFD"), "\x00", "")
+}
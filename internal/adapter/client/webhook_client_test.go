@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// The response body is kept as valid utf-8 without NUL, so the delivery attempt can be stored (text column)
+func TestSendResponseBody(t *testing.T) {
+	tests := []struct {
+		name	string
+		body	string
+		want	string
+	}{
+		{
+			name: "plain body",
+			body: `{"status":"ok"}`,
+			want: `{"status":"ok"}`,
+		},
+		{
+			name: "multibyte rune split by the limit",
+			body: strings.Repeat("a", responseBodyLimit - 1) + "é",
+			want: strings.Repeat("a", responseBodyLimit - 1),
+		},
+		{
+			name: "4 bytes rune split by the limit",
+			body: strings.Repeat("a", responseBodyLimit - 2) + "😀",
+			want: strings.Repeat("a", responseBodyLimit - 2),
+		},
+		{
+			name: "nul byte",
+			body: "ok\x00done",
+			want: "okdone",
+		},
+		{
+			name: "invalid utf-8",
+			body: "ok\xffdone",
+			want: "ok�done",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.body))
+			}))
+			defer receiver.Close()
+
+			webHookClient := NewWebHookClient(5 * time.Second)
+			res, err := webHookClient.Send(context.Background(), http.MethodPost, receiver.URL, map[string]string{}, []byte(`{}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Body != tt.want {
+				t.Fatalf("got body %q, want %q", res.Body, tt.want)
+			}
+			if !utf8.ValidString(res.Body) || strings.Contains(res.Body, "\x00") {
+				t.Fatalf("got body %q, not storable as text", res.Body)
+			}
+		})
+	}
+}
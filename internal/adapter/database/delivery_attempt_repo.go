@@ -0,0 +1,110 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-worker-webhook/internal/core/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// About insert a delivery attempt
+func (w *WorkerRepository) InsertDeliveryAttempt(ctx context.Context, tx pgx.Tx, deliveryAttempt model.DeliveryAttempt) (*model.DeliveryAttempt, error){
+	childLogger.Info().Str("func","InsertDeliveryAttempt").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+
+	// Trace
+	span := tracerProvider.Span(ctx, "database.InsertDeliveryAttempt")
+	defer span.End()
+
+	// Query and execute
+	query := `INSERT INTO webhook_delivery_attempt (	webhook_transaction_id,
+														attempt,
+														request_headers,
+														response_status,
+														response_body,
+														latency_ms,
+														error_class,
+														error,
+														pod_name,
+														created_at)
+				VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`
+
+	row := tx.QueryRow(	ctx,
+						query,
+						deliveryAttempt.WebHookID,
+						deliveryAttempt.Attempt,
+						deliveryAttempt.RequestHeaders,
+						deliveryAttempt.ResponseStatus,
+						deliveryAttempt.ResponseBody,
+						deliveryAttempt.Latency,
+						deliveryAttempt.ErrorClass,
+						deliveryAttempt.Error,
+						deliveryAttempt.PodName,
+						deliveryAttempt.CreatedAt)
+
+	if err := row.Scan(&deliveryAttempt.ID); err != nil {
+		return nil, errors.New(err.Error())
+	}
+
+	return &deliveryAttempt, nil
+}
+
+// About list the delivery attempts of a webhook
+func (w WorkerRepository) ListDeliveryAttempts(ctx context.Context, webhookID int) (*[]model.DeliveryAttempt, error){
+	childLogger.Info().Str("func","ListDeliveryAttempts").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+
+	span := tracerProvider.Span(ctx, "database.ListDeliveryAttempts")
+	defer span.End()
+
+	conn, err := w.DatabasePGServer.Acquire(ctx)
+	if err != nil {
+		return nil, errors.New(err.Error())
+	}
+	defer w.DatabasePGServer.Release(conn)
+
+	res_attempt_list := []model.DeliveryAttempt{}
+
+	query := `SELECT id,
+					webhook_transaction_id,
+					attempt,
+					request_headers,
+					response_status,
+					response_body,
+					latency_ms,
+					error_class,
+					error,
+					pod_name,
+					created_at
+				FROM public.webhook_delivery_attempt
+				WHERE webhook_transaction_id = $1
+				order by attempt asc, created_at asc`
+
+	rows, err := conn.Query(ctx, query, webhookID)
+	if err != nil {
+		return nil, errors.New(err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		res_attempt := model.DeliveryAttempt{}
+		err := rows.Scan( 	&res_attempt.ID,
+							&res_attempt.WebHookID,
+							&res_attempt.Attempt,
+							&res_attempt.RequestHeaders,
+							&res_attempt.ResponseStatus,
+							&res_attempt.ResponseBody,
+							&res_attempt.Latency,
+							&res_attempt.ErrorClass,
+							&res_attempt.Error,
+							&res_attempt.PodName,
+							&res_attempt.CreatedAt,
+						)
+		if err != nil {
+			return nil, errors.New(err.Error())
+        }
+		res_attempt_list = append(res_attempt_list, res_attempt)
+	}
+
+	return &res_attempt_list, nil
+}
@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-worker-webhook/internal/core/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// About get the signing keys still published (active or retired inside the publish window)
+func (w WorkerRepository) GetSigningKeys(ctx context.Context, publishTime int) (*[]model.SigningKey, error){
+	childLogger.Debug().Str("func","GetSigningKeys").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+
+	span := tracerProvider.Span(ctx, "database.GetSigningKeys")
+	defer span.End()
+
+	conn, err := w.DatabasePGServer.Acquire(ctx)
+	if err != nil {
+		return nil, errors.New(err.Error())
+	}
+	defer w.DatabasePGServer.Release(conn)
+
+	res_key_list := []model.SigningKey{}
+
+	query := `SELECT kid,
+					algorithm,
+					private_key,
+					key_encrypted,
+					public_key,
+					created_at,
+					active_at,
+					retired_at
+				FROM public.webhook_signing_key
+				WHERE retired_at is null
+				or retired_at > now() - make_interval(secs => $1)
+				order by created_at desc`
+
+	rows, err := conn.Query(ctx, query, publishTime)
+	if err != nil {
+		return nil, errors.New(err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		res_key := model.SigningKey{}
+		err := rows.Scan( 	&res_key.KeyID,
+							&res_key.Algorithm,
+							&res_key.PrivateKey,
+							&res_key.Encrypted,
+							&res_key.PublicKey,
+							&res_key.CreatedAt,
+							&res_key.ActiveAt,
+							&res_key.RetiredAt,
+						)
+		if err != nil {
+			return nil, errors.New(err.Error())
+        }
+		res_key_list = append(res_key_list, res_key)
+	}
+	
+	return &res_key_list, nil
+}
+
+// About insert a signing key, it signs after activeDelay (published before it)
+func (w *WorkerRepository) InsertSigningKey(ctx context.Context, tx pgx.Tx, signingKey model.SigningKey, activeDelay int) (*model.SigningKey, error){
+	childLogger.Info().Str("func","InsertSigningKey").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+
+	span := tracerProvider.Span(ctx, "database.InsertSigningKey")
+	defer span.End()
+
+	query := `INSERT INTO webhook_signing_key (	kid,
+												algorithm,
+												private_key,
+												key_encrypted,
+												public_key,
+												created_at,
+												active_at)
+				VALUES($1, $2, $3, $4, $5, $6, now() + make_interval(secs => $7))
+				RETURNING active_at`
+
+	err := tx.QueryRow(	ctx,
+						query,
+						signingKey.KeyID,
+						signingKey.Algorithm,
+						signingKey.PrivateKey,
+						signingKey.Encrypted,
+						signingKey.PublicKey,
+						signingKey.CreatedAt,
+						activeDelay).Scan(&signingKey.ActiveAt)
+	if err != nil {
+		return nil, errors.New(err.Error())
+	}
+
+	return &signingKey, nil
+}
+
+// About retire the signing keys not retired yet after retireDelay, when the new key is active
+// (they stay published for the publish window)
+func (w *WorkerRepository) RetireSigningKeys(ctx context.Context, tx pgx.Tx, retireDelay int) (int64, error){
+	childLogger.Info().Str("func","RetireSigningKeys").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+
+	span := tracerProvider.Span(ctx, "database.RetireSigningKeys")
+	defer span.End()
+
+	query := `UPDATE webhook_signing_key
+				SET retired_at = now() + make_interval(secs => $1)
+				WHERE retired_at is null`
+
+	row, err := tx.Exec(ctx, query, retireDelay)
+	if err != nil {
+		return 0, errors.New(err.Error())
+	}
+	return row.RowsAffected(), nil
+}
+
+// About lock the signing keys until the end of the transaction (serialize the key creation across the replicas)
+func (w *WorkerRepository) LockSigningKeys(ctx context.Context, tx pgx.Tx) error{
+	childLogger.Debug().Str("func","LockSigningKeys").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+
+	span := tracerProvider.Span(ctx, "database.LockSigningKeys")
+	defer span.End()
+
+	query := `SELECT pg_advisory_xact_lock(hashtext('webhook_signing_key'))`
+
+	_, err := tx.Exec(ctx, query)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	return nil
+}
+
+// About check there is an active signing key (inside the transaction)
+func (w *WorkerRepository) HasActiveSigningKey(ctx context.Context, tx pgx.Tx) (bool, error){
+	childLogger.Debug().Str("func","HasActiveSigningKey").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+
+	span := tracerProvider.Span(ctx, "database.HasActiveSigningKey")
+	defer span.End()
+
+	query := `SELECT exists (	SELECT 1
+								FROM public.webhook_signing_key
+								WHERE retired_at is null)`
+
+	var active bool
+	err := tx.QueryRow(ctx, query).Scan(&active)
+	if err != nil {
+		return false, errors.New(err.Error())
+	}
+	return active, nil
+}
@@ -6,7 +6,6 @@ import (
 	"errors"
 	
 	"github.com/go-worker-webhook/internal/core/model"
-	"github.com/go-worker-webhook/internal/core/erro"
 
 	go_core_observ "github.com/eliezerraj/go-core/observability"
 	go_core_pg "github.com/eliezerraj/go-core/database/pg"
@@ -67,11 +66,11 @@ func (w WorkerRepository) GetTransactionUUID(ctx context.Context) (*string, erro
 	return &uuid, nil
 }
 
-// About get webhook
-func (w WorkerRepository) GetSetupWebHook(ctx context.Context, webhook model.WebHook) (*model.WebHook, error){
-	childLogger.Info().Str("func","GetSetupWebHook").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+// About get all the webhook_config (subscriptions) of the receivers (receiver and type), in one query
+func (w WorkerRepository) GetSetupWebHooks(ctx context.Context, webhooks []model.WebHook) (*[]model.WebHook, error){
+	childLogger.Info().Str("func","GetSetupWebHooks").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
 
-	span := tracerProvider.Span(ctx, "database.GetSetupWebHook")
+	span := tracerProvider.Span(ctx, "database.GetSetupWebHooks")
 	defer span.End()
 
 	conn, err := w.DatabasePGServer.Acquire(ctx)
@@ -80,7 +79,7 @@ func (w WorkerRepository) GetSetupWebHook(ctx context.Context, webhook model.Web
 	}
 	defer w.DatabasePGServer.Release(conn)
 
-	res_webhook := model.WebHook{}
+	res_webhook_list := []model.WebHook{}
 
 	query := `SELECT id,
 					receiver,
@@ -88,41 +87,61 @@ func (w WorkerRepository) GetSetupWebHook(ctx context.Context, webhook model.Web
 					type,	 
 					url,
 					method,
+					ordering_mode,
 					created_at,
 					updated_at 
 				FROM public.webhook_config 
-				WHERE receiver = $1
-				and	type = $2`
+				WHERE (receiver, type) IN (SELECT * FROM unnest($1::text[], $2::text[]))
+				and enabled = true
+				order by id asc`
+
+	receivers := make([]string, 0, len(webhooks))
+	types := make([]string, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		receivers = append(receivers, webhook.Receiver)
+		types = append(types, webhook.Type)
+	}
 
-	rows, err := conn.Query(ctx, query, webhook.Receiver, webhook.Type)
+	rows, err := conn.Query(ctx, query, receivers, types)
 	if err != nil {
 		return nil, errors.New(err.Error())
 	}
 	defer rows.Close()
 
 	for rows.Next() {
+		res_webhook := model.WebHook{}
 		err := rows.Scan( 	&res_webhook.ID,
 							&res_webhook.Receiver, 
 							&res_webhook.Host,
 							&res_webhook.Type,  
 							&res_webhook.Url, 
 							&res_webhook.Method, 
+							&res_webhook.OrderingMode,
 							&res_webhook.CreatedAt,
 							&res_webhook.UpdatedAt,)
 		if err != nil {
 			return nil, errors.New(err.Error())
         }
-		return &res_webhook, nil
+		res_webhook_list = append(res_webhook_list, res_webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New(err.Error())
 	}
 	
-	return nil, erro.ErrNotFound
+	return &res_webhook_list, nil
 }
 
-// About get all webhook waiting for sending
-func (w WorkerRepository) GetWebHook(ctx context.Context, webhook *model.WebHook) (*model.WebHook, error){
-	childLogger.Debug().Str("func","GetWebHook").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
-
-	span := tracerProvider.Span(ctx, "database.GetWebHook")
+// About claim a batch of webhook due for sending
+// The rows are leased to the owner, locked rows (claimed by another replica right now) are skipped
+// and rows whose lease expired (owner crashed) are claimed again
+// The query starts from the due rows (an idle poll touches no webhook_config), each enabled webhook_config
+// with due rows gives at most limit candidates (index webhook_config_id, next_attempt_at), ranked per
+// webhook_config (the key of the weight). The locking select checks the due and lease filters again,
+// a row leased by another replica meanwhile is rechecked after the lock and left out
+func (w WorkerRepository) ClaimWebHooks(ctx context.Context, webhook *model.WebHook, leaseTime int, limit int) (*[]model.WebHook, error){
+	childLogger.Debug().Str("func","ClaimWebHooks").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+
+	span := tracerProvider.Span(ctx, "database.ClaimWebHooks")
 	defer span.End()
 
 	conn, err := w.DatabasePGServer.Acquire(ctx)
@@ -131,80 +150,419 @@ func (w WorkerRepository) GetWebHook(ctx context.Context, webhook *model.WebHook
 	}
 	defer w.DatabasePGServer.Release(conn)
 
-	res_webhook := model.WebHook{}
-
-	query := `SELECT id,
-					host,	 
-					url,
-					method,
-					payload,
-					status,
-					created_at,
-					updated_at 
-				FROM public.webhook_transaction 
-				WHERE status =$1
-				order by created_at asc`
-
-	rows, err := conn.Query(ctx, query, webhook.Status)
+	res_webhook_list := []model.WebHook{}
+
+	query := `WITH claimed AS (
+				UPDATE public.webhook_transaction
+				SET claimed_by = $1,
+					lease_expires_at = now() + make_interval(secs => $2)
+				WHERE id IN (	SELECT id
+								FROM public.webhook_transaction
+								WHERE id IN (	SELECT ranked.id
+												FROM (	SELECT 	due.id,
+																due.next_attempt_at,
+																ROW_NUMBER() OVER (PARTITION BY d.id ORDER BY due.next_attempt_at) as rn,
+																GREATEST(d.weight, 1) as weight
+														FROM (	SELECT DISTINCT webhook_config_id
+																FROM public.webhook_transaction
+																WHERE next_attempt_at <= now()
+																and (lease_expires_at is null or lease_expires_at < now())) due_config
+														JOIN public.webhook_config d on d.id = due_config.webhook_config_id
+																					and d.enabled = true
+														CROSS JOIN LATERAL (SELECT 	t.id,
+																					t.next_attempt_at
+																			FROM public.webhook_transaction t
+																			WHERE t.webhook_config_id = d.id
+																			and t.next_attempt_at <= now()
+																			and (t.lease_expires_at is null or t.lease_expires_at < now())
+																			and not exists (SELECT 1
+																							FROM public.webhook_transaction p
+																							WHERE p.ordering_key = t.ordering_key
+																							and p.sequence_no < t.sequence_no
+																							and p.next_attempt_at is not null)
+																			order by t.next_attempt_at asc
+																			limit $3) due) ranked
+												order by (ranked.rn - 1) / ranked.weight asc, ranked.next_attempt_at asc
+												limit $3)
+								and next_attempt_at <= now()
+								and (lease_expires_at is null or lease_expires_at < now())
+								FOR UPDATE SKIP LOCKED)
+				RETURNING *)
+			SELECT 	claimed.id,
+					claimed.webhook_config_id,
+					claimed.receiver,
+					claimed.host,	 
+					claimed.url,
+					claimed.method,
+					claimed.payload,
+					claimed.status,
+					claimed.claimed_by,
+					claimed.lease_expires_at,
+					claimed.attempts,
+					claimed.next_attempt_at,
+					claimed.sequence_no,
+					claimed.created_at,
+					claimed.updated_at,
+					c.retry_max_attempts,
+					c.retry_base_delay_ms,
+					c.retry_max_delay_ms,
+					c.signature_mode,
+					c.signing_secret,
+					c.signing_secret_previous,
+					c.signing_secret_previous_expires_at,
+					c.response_rules,
+					c.rate_limit_rps,
+					c.rate_limit_burst,
+					c.max_in_flight,
+					c.weight
+			FROM claimed
+			LEFT JOIN public.webhook_config c on c.id = claimed.webhook_config_id
+			order by claimed.next_attempt_at asc`
+
+	rows, err := conn.Query(ctx, query, webhook.ClaimedBy, leaseTime, limit)
 	if err != nil {
 		return nil, errors.New(err.Error())
 	}
 	defer rows.Close()
 
 	for rows.Next() {
+		res_webhook := model.WebHook{}
+		var configID, maxAttempts, baseDelay, maxDelay *int
+		var signatureMode, signingSecret, signingSecretPrevious *string
+		var rateLimitRPS *float64
+		var sequenceNo *int64
+		var rateLimitBurst, maxInFlight, weight *int
 		err := rows.Scan( 	&res_webhook.ID,
+							&configID,
+							&res_webhook.Receiver,
 							&res_webhook.Host, 
 							&res_webhook.Url, 
 							&res_webhook.Method, 
 							&res_webhook.Payload, 
 							&res_webhook.Status, 
+							&res_webhook.ClaimedBy,
+							&res_webhook.LeaseExpiresAt,
+							&res_webhook.Attempts,
+							&res_webhook.NextAttemptAt,
+							&sequenceNo,
 							&res_webhook.CreatedAt,
 							&res_webhook.UpdatedAt,
+							&maxAttempts,
+							&baseDelay,
+							&maxDelay,
+							&signatureMode,
+							&signingSecret,
+							&signingSecretPrevious,
+							&res_webhook.SigningSecretPreviousExpiresAt,
+							&res_webhook.ResponseRules,
+							&rateLimitRPS,
+							&rateLimitBurst,
+							&maxInFlight,
+							&weight,
 						)
 		if err != nil {
 			return nil, errors.New(err.Error())
         }
-		return &res_webhook, nil
+		if configID != nil {
+			res_webhook.ConfigID = *configID
+		}
+		// retry policy override of the webhook_config (0 means use the default)
+		res_webhook.RetryPolicy = &model.RetryPolicy{}
+		if maxAttempts != nil {
+			res_webhook.RetryPolicy.MaxAttempts = *maxAttempts
+		}
+		if baseDelay != nil {
+			res_webhook.RetryPolicy.BaseDelay = *baseDelay
+		}
+		if maxDelay != nil {
+			res_webhook.RetryPolicy.MaxDelay = *maxDelay
+		}
+		if signatureMode != nil {
+			res_webhook.SignatureMode = *signatureMode
+		}
+		if signingSecret != nil {
+			res_webhook.SigningSecret = *signingSecret
+		}
+		if signingSecretPrevious != nil {
+			res_webhook.SigningSecretPrevious = *signingSecretPrevious
+		}
+		if sequenceNo != nil {
+			res_webhook.SequenceNo = *sequenceNo
+		}
+		res_webhook.Weight = 1
+		if weight != nil && *weight > 1 {
+			res_webhook.Weight = *weight
+		}
+		// rate limit of the webhook_config (null means no limit)
+		if rateLimitRPS != nil || maxInFlight != nil {
+			res_webhook.RateLimit = &model.RateLimit{}
+			if rateLimitRPS != nil {
+				res_webhook.RateLimit.RPS = *rateLimitRPS
+			}
+			if rateLimitBurst != nil {
+				res_webhook.RateLimit.Burst = *rateLimitBurst
+			}
+			if maxInFlight != nil {
+				res_webhook.RateLimit.MaxInFlight = *maxInFlight
+			}
+		}
+		res_webhook_list = append(res_webhook_list, res_webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New(err.Error())
 	}
 	
-	return nil, erro.ErrNotFound
+	return &res_webhook_list, nil
+}
+
+// About release the lease of a webhook not sent
+func (w WorkerRepository) ReleaseWebHook(ctx context.Context, webHook model.WebHook) (int64, error){
+	childLogger.Debug().Str("func","ReleaseWebHook").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+
+	span := tracerProvider.Span(ctx, "database.ReleaseWebHook")
+	defer span.End()
+
+	conn, err := w.DatabasePGServer.Acquire(ctx)
+	if err != nil {
+		return 0, errors.New(err.Error())
+	}
+	defer w.DatabasePGServer.Release(conn)
+
+	query := `UPDATE public.webhook_transaction
+				SET claimed_by = null,
+					lease_expires_at = null
+				WHERE id = $1
+				and claimed_by = $2`
+
+	row, err := conn.Exec(ctx, query, webHook.ID, webHook.ClaimedBy)
+	if err != nil {
+		return 0, errors.New(err.Error())
+	}
+	return row.RowsAffected(), nil
+}
+
+// About renew the lease of a claimed webhook, only while the lease is still held
+func (w WorkerRepository) RenewWebHook(ctx context.Context, webHook model.WebHook, leaseTime int) (int64, error){
+	childLogger.Debug().Str("func","RenewWebHook").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+
+	span := tracerProvider.Span(ctx, "database.RenewWebHook")
+	defer span.End()
+
+	conn, err := w.DatabasePGServer.Acquire(ctx)
+	if err != nil {
+		return 0, errors.New(err.Error())
+	}
+	defer w.DatabasePGServer.Release(conn)
+
+	query := `UPDATE public.webhook_transaction
+				SET lease_expires_at = now() + make_interval(secs => $3)
+				WHERE id = $1
+				and claimed_by = $2
+				and lease_expires_at > now()`
+
+	row, err := conn.Exec(ctx, query, webHook.ID, webHook.ClaimedBy, leaseTime)
+	if err != nil {
+		return 0, errors.New(err.Error())
+	}
+	return row.RowsAffected(), nil
+}
+
+// About count the webhooks pending delivery (waiting for send or retrying)
+// Only the rows the dispatcher claims (next_attempt_at of an enabled webhook_config), the backlog of a disabled
+// webhook_config or a row of the first version without next_attempt_at never drains, it would hold the consumer paused
+func (w WorkerRepository) CountPendingWebHooks(ctx context.Context) (int64, error){
+	childLogger.Debug().Str("func","CountPendingWebHooks").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+
+	span := tracerProvider.Span(ctx, "database.CountPendingWebHooks")
+	defer span.End()
+
+	conn, err := w.DatabasePGServer.Acquire(ctx)
+	if err != nil {
+		return 0, errors.New(err.Error())
+	}
+	defer w.DatabasePGServer.Release(conn)
+
+	query := `SELECT count(*)
+				FROM public.webhook_transaction t
+				JOIN public.webhook_config d on d.id = t.webhook_config_id
+											and d.enabled = true
+				WHERE t.next_attempt_at is not null`
+
+	var count int64
+	err = conn.QueryRow(ctx, query).Scan(&count)
+	if err != nil {
+		return 0, errors.New(err.Error())
+	}
+
+	return count, nil
+}
+
+// About postpone a claimed webhook without spending an attempt (and release the lease)
+func (w WorkerRepository) DeferWebHook(ctx context.Context, webHook model.WebHook) (int64, error){
+	childLogger.Debug().Str("func","DeferWebHook").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+
+	span := tracerProvider.Span(ctx, "database.DeferWebHook")
+	defer span.End()
+
+	conn, err := w.DatabasePGServer.Acquire(ctx)
+	if err != nil {
+		return 0, errors.New(err.Error())
+	}
+	defer w.DatabasePGServer.Release(conn)
+
+	query := `UPDATE public.webhook_transaction
+				SET next_attempt_at = $3,
+					claimed_by = null,
+					lease_expires_at = null
+				WHERE id = $1
+				and claimed_by = $2`
+
+	row, err := conn.Exec(ctx, query, webHook.ID, webHook.ClaimedBy, webHook.NextAttemptAt)
+	if err != nil {
+		return 0, errors.New(err.Error())
+	}
+	return row.RowsAffected(), nil
 }
 
-// About insert webhook
-func (w *WorkerRepository) InsertWebHook(ctx context.Context, tx pgx.Tx, webHook model.WebHook) (*model.WebHook, error){
-	childLogger.Info().Str("func","InsertWebHook").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+// About insert a batch of webhooks (pgx batch, one round trip)
+// Returns the id of each webhook, 0 when the event was already recorded for the receiver
+func (w *WorkerRepository) InsertWebHooks(ctx context.Context, tx pgx.Tx, webHooks []model.WebHook) ([]int, error){
+	childLogger.Info().Str("func","InsertWebHooks").Int("webhooks", len(webHooks)).Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
 
 	// Trace
-	span := tracerProvider.Span(ctx, "database.InsertWebHook")
+	span := tracerProvider.Span(ctx, "database.InsertWebHooks")
 	defer span.End()
 
 	// Query and execute
 	query := 	`INSERT INTO webhook_transaction (	receiver,
+													webhook_config_id,
 													host,
 													url,
 													method, 
 													payload,
 													status,
+													error_class,
+													next_attempt_at,
+													ordering_key,
+													kafka_topic,
+													kafka_partition,
+													kafka_offset,
+													kafka_key,
+													event_id,
 													created_at) 
-				VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id`
-	row	:= tx.QueryRow(	ctx,
-						query,
-						webHook.Receiver,
-						webHook.Host,
-						webHook.Url,
-						webHook.Method,
-						webHook.Payload,
-						webHook.Status,
-						time.Now())
-	var id int
-	
-	if err := row.Scan(&id); err != nil {
-		return nil, errors.New(err.Error())
+				VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+				ON CONFLICT (event_id, receiver, (COALESCE(webhook_config_id, 0))) WHERE event_id IS NOT NULL
+				DO NOTHING
+				RETURNING id`
+
+	batch := &pgx.Batch{}
+	for _, webHook := range webHooks {
+		// a webhook without setup is never scheduled
+		var configID *int
+		if webHook.ConfigID != 0 {
+			configID = &webHook.ConfigID
+		}
+		// only the ordered webhooks have an ordering key
+		var orderingKey *string
+		if webHook.OrderingKey != "" {
+			orderingKey = &webHook.OrderingKey
+		}
+		// only the consumed webhooks have kafka coordinates and event id
+		var kafkaTopic, kafkaKey, eventID *string
+		var kafkaPartition *int32
+		var kafkaOffset *int64
+		if webHook.Topic != "" {
+			kafkaTopic = &webHook.Topic
+			kafkaPartition = &webHook.KafkaPartition
+			kafkaOffset = &webHook.KafkaOffset
+			kafkaKey = &webHook.KafkaKey
+		}
+		if webHook.EventID != "" {
+			eventID = &webHook.EventID
+		}
+
+		batch.Queue(query,
+					webHook.Receiver,
+					configID,
+					webHook.Host,
+					webHook.Url,
+					webHook.Method,
+					webHook.Payload,
+					webHook.Status,
+					webHook.ErrorClass,
+					webHook.NextAttemptAt,
+					orderingKey,
+					kafkaTopic,
+					kafkaPartition,
+					kafkaOffset,
+					kafkaKey,
+					eventID,
+					time.Now())
 	}
 
-	webHook.ID = id
+	results := tx.SendBatch(ctx, batch)
+	defer results.Close()
+
+	list_id := make([]int, len(webHooks))
+	for i := range webHooks {
+		err := results.QueryRow().Scan(&list_id[i])
+		if err != nil {
+			// nothing inserted, the event was already recorded for the receiver
+			if errors.Is(err, pgx.ErrNoRows) {
+				list_id[i] = 0
+				continue
+			}
+			return nil, errors.New(err.Error())
+		}
+	}
+
+	return list_id, nil
+}
+
+// About allocate the next sequence of an ordering key
+// The upsert locks the sequence row until the end of the transaction, so the sequences follow the commit order
+func (w *WorkerRepository) NextSequence(ctx context.Context, tx pgx.Tx, orderingKey string) (int64, error){
+	childLogger.Debug().Str("func","NextSequence").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+
+	// Trace
+	span := tracerProvider.Span(ctx, "database.NextSequence")
+	defer span.End()
+
+	// Query and execute
+	query := `INSERT INTO webhook_ordering_sequence (ordering_key, last_sequence_no, updated_at)
+				VALUES($1, 1, now())
+				ON CONFLICT (ordering_key) 
+				DO UPDATE SET last_sequence_no = webhook_ordering_sequence.last_sequence_no + 1,
+							updated_at = now()
+				RETURNING last_sequence_no`
+
+	var sequenceNo int64
+	row := tx.QueryRow(ctx, query, orderingKey)
+	if err := row.Scan(&sequenceNo); err != nil {
+		return 0, errors.New(err.Error())
+	}
 
-	return &webHook, nil
+	return sequenceNo, nil
+}
+
+// About set the sequence of an ordered webhook
+func (w *WorkerRepository) SetSequenceWebHook(ctx context.Context, tx pgx.Tx, webHook model.WebHook) (int64, error){
+	childLogger.Debug().Str("func","SetSequenceWebHook").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+
+	// Trace
+	span := tracerProvider.Span(ctx, "database.SetSequenceWebHook")
+	defer span.End()
+
+	// Query and execute
+	query := `UPDATE webhook_transaction
+				SET sequence_no = $2
+				WHERE id = $1`
+
+	row, err := tx.Exec(ctx, query, webHook.ID, webHook.SequenceNo)
+	if err != nil {
+		return 0, errors.New(err.Error())
+	}
+	return row.RowsAffected(), nil
 }
 
 // About update webhook
@@ -215,17 +573,105 @@ func (w *WorkerRepository) UpdateWebHook(ctx context.Context, tx pgx.Tx, webHook
 	span := tracerProvider.Span(ctx, "database.UpdateWebHook")
 	defer span.End()
 
-	// Query and execute
+	// Query and execute (only the lease owner is allowed to update)
 	query := `UPDATE webhook_transaction
 				SET status = $2,
-					updated_at = $3
-				WHERE id = $1`
+					updated_at = $3,
+					attempts = $5,
+					next_attempt_at = $6,
+					last_error = $7,
+					error_class = $8,
+					claimed_by = null,
+					lease_expires_at = null
+				WHERE id = $1
+				and claimed_by = $4`
 
 	row, err := tx.Exec(ctx, 
 						query,	
 						webHook.ID,
 						webHook.Status,
-						time.Now())
+						time.Now(),
+						webHook.ClaimedBy,
+						webHook.Attempts,
+						webHook.NextAttemptAt,
+						webHook.LastError,
+						webHook.ErrorClass)
+	if err != nil {
+		return 0, errors.New(err.Error())
+	}
+	return row.RowsAffected(), nil
+}
+
+// About put the dead-letter webhooks back in queue with a fresh attempt budget
+func (w *WorkerRepository) ReplayWebHooks(ctx context.Context, tx pgx.Tx, replayFilter model.ReplayFilter) (int64, error){
+	childLogger.Info().Str("func","ReplayWebHooks").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+
+	// Trace
+	span := tracerProvider.Span(ctx, "database.ReplayWebHooks")
+	defer span.End()
+
+	// the zero value of a filter means not filtered
+	var id *int
+	var receiver, errorClass *string
+	if replayFilter.ID != 0 {
+		id = &replayFilter.ID
+	}
+	if replayFilter.Receiver != "" {
+		receiver = &replayFilter.Receiver
+	}
+	if replayFilter.ErrorClass != "" {
+		errorClass = &replayFilter.ErrorClass
+	}
+
+	// Query and execute
+	query := `UPDATE webhook_transaction
+				SET status = $1,
+					attempts = 0,
+					next_attempt_at = now(),
+					last_error = null,
+					error_class = null,
+					replayed_by = $3,
+					replayed_at = now(),
+					updated_at = now()
+				WHERE status = $2
+				and ($4::int is null or id = $4)
+				and ($5::varchar is null or receiver = $5)
+				and ($6::timestamptz is null or created_at >= $6)
+				and ($7::timestamptz is null or created_at < $7)
+				and ($8::varchar is null or error_class = $8)`
+
+	row, err := tx.Exec(ctx, 
+						query,	
+						"IN-QUEUE:WAITING-FOR-SEND",
+						"IN-QUEUE:DEAD-LETTER",
+						replayFilter.ReplayedBy,
+						id,
+						receiver,
+						replayFilter.From,
+						replayFilter.To,
+						errorClass)
+	if err != nil {
+		return 0, errors.New(err.Error())
+	}
+	return row.RowsAffected(), nil
+}
+
+// About disable a webhook_config (endpoint gone), its webhooks are not claimed until it is enabled again
+func (w *WorkerRepository) DisableWebHookConfig(ctx context.Context, tx pgx.Tx, id int, reason string) (int64, error){
+	childLogger.Info().Str("func","DisableWebHookConfig").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+
+	// Trace
+	span := tracerProvider.Span(ctx, "database.DisableWebHookConfig")
+	defer span.End()
+
+	// Query and execute
+	query := `UPDATE webhook_config
+				SET enabled = false,
+					disabled_reason = $2,
+					updated_at = now()
+				WHERE id = $1`
+
+	row, err := tx.Exec(ctx, query, id, reason)
 	if err != nil {
 		return 0, errors.New(err.Error())
 	}
@@ -2,37 +2,244 @@ package event
 
 import (
 	"context"
+	"errors"
+	"strings"
+	"time"
 
 	go_core_observ "github.com/eliezerraj/go-core/observability"
 	go_core_event "github.com/eliezerraj/go-core/event/kafka"
 
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/rs/zerolog/log"
 )
 
 var childLogger = log.With().Str("component","go-worker-webhook").Str("package","internal.adapter.event").Logger()
 
 var tracerProvider go_core_observ.TracerProvider
-var consumerWorker go_core_event.ConsumerWorker
+
+// Message consumed, with its kafka coordinates
+type Message struct {
+	Topic		string
+	Partition	int32
+	Offset		int64
+	Key			string
+	Header		map[string]string
+	Payload		[]byte
+}
+
+var ErrNoDeadLetter = errors.New("dead-letter topic not configured")
 
 type WorkerEvent struct {
 	Topics	[]string
-	WorkerKafka *go_core_event.ConsumerWorker 
+	consumer *kafka.Consumer
+	producer *kafka.Producer
+	dlqTopic string
+	rebalanced func(topic string, partition int32)
 }
 
-func NewWorkerEvent(ctx context.Context, topics []string, kafkaConfigurations *go_core_event.KafkaConfigurations) (*WorkerEvent, error) {
+// About create a kafka consumer (offsets are committed by the worker)
+func NewWorkerEvent(ctx context.Context, topics []string, dlqTopic string, kafkaConfigurations *go_core_event.KafkaConfigurations) (*WorkerEvent, error) {
 	childLogger.Info().Str("func","NewWorkerEvent").Send()
 
 	//trace
 	span := tracerProvider.Span(ctx, "adapter.event.NewWorkerEvent")
 	defer span.End()
 
-	workerKafka, err := consumerWorker.NewConsumerWorker(kafkaConfigurations)
+	configMap := &kafka.ConfigMap{	"bootstrap.servers": brokersOf(kafkaConfigurations),
+									"security.protocol": kafkaConfigurations.Protocol,
+									"sasl.mechanisms": kafkaConfigurations.Mechanisms,
+									"sasl.username": kafkaConfigurations.Username,
+									"sasl.password": kafkaConfigurations.Password,
+									"client.id": kafkaConfigurations.Clientid,
+									"group.id": kafkaConfigurations.Groupid,
+									"broker.address.family": "v4",
+									"session.timeout.ms": 6000,
+									"enable.auto.commit": false,
+									"auto.offset.reset": "earliest",
+								}
+
+	consumer, err := kafka.NewConsumer(configMap)
 	if err != nil {
 		return nil, err
 	}
 
-	return &WorkerEvent{
+	workerEvent := &WorkerEvent{
 		Topics: topics,
-		WorkerKafka: workerKafka,
-	},nil
-}
\ No newline at end of file
+		consumer: consumer,
+		dlqTopic: dlqTopic,
+	}
+
+	err = consumer.SubscribeTopics(topics, workerEvent.rebalance)
+	if err != nil {
+		consumer.Close()
+		return nil, err
+	}
+
+	// the dead-letter topic is optional
+	if dlqTopic != "" {
+		workerEvent.producer, err = newProducer(kafkaConfigurations)
+		if err != nil {
+			consumer.Close()
+			return nil, err
+		}
+	}
+
+	return workerEvent, nil
+}
+
+// About register the callback of the partitions revoked or assigned in a rebalance
+// It is called by the goroutine of Consumer, it must be registered before it starts
+func (w *WorkerEvent) OnRebalance(callback func(topic string, partition int32)) {
+	w.rebalanced = callback
+}
+
+// About the rebalance of the consumer group (the assignment itself is done by the library)
+func (w *WorkerEvent) rebalance(consumer *kafka.Consumer, ev kafka.Event) error {
+	var topicPartitions []kafka.TopicPartition
+	switch e := ev.(type) {
+	case kafka.AssignedPartitions:
+		topicPartitions = e.Partitions
+	case kafka.RevokedPartitions:
+		topicPartitions = e.Partitions
+	}
+	childLogger.Info().Str("func","rebalance").Str("event", ev.String()).Send()
+
+	if w.rebalanced == nil {
+		return nil
+	}
+	for _, topicPartition := range topicPartitions {
+		if topicPartition.Topic != nil {
+			w.rebalanced(*topicPartition.Topic, topicPartition.Partition)
+		}
+	}
+	return nil
+}
+
+// About the bootstrap servers
+func brokersOf(kafkaConfigurations *go_core_event.KafkaConfigurations) string {
+	brokers := []string{}
+	for _, broker := range []string{kafkaConfigurations.Brokers1, kafkaConfigurations.Brokers2, kafkaConfigurations.Brokers3} {
+		if broker != "" {
+			brokers = append(brokers, broker)
+		}
+	}
+	return strings.Join(brokers, ",")
+}
+
+// About consume the messages until the context is done, then close the channel
+func (w *WorkerEvent) Consumer(ctx context.Context, messages chan<- Message) {
+	childLogger.Info().Str("func","Consumer").Interface("topics", w.Topics).Send()
+
+	defer close(messages)
+
+	for ctx.Err() == nil {
+		msg, err := w.consumer.ReadMessage(100 * time.Millisecond)
+		if err != nil {
+			if kafkaErr, ok := err.(kafka.Error); ok && kafkaErr.Code() == kafka.ErrTimedOut {
+				continue
+			}
+			childLogger.Error().Err(err).Msg("error read message")
+			continue
+		}
+
+		header := make(map[string]string)
+		for _, kafkaHeader := range msg.Headers {
+			header[kafkaHeader.Key] = string(kafkaHeader.Value)
+		}
+
+		message := Message{
+			Topic: *msg.TopicPartition.Topic,
+			Partition: msg.TopicPartition.Partition,
+			Offset: int64(msg.TopicPartition.Offset),
+			Key: string(msg.Key),
+			Header: header,
+			Payload: msg.Value,
+		}
+
+		select {
+		case messages <- message:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// About commit the offset of a message (the next offset to be read)
+func (w *WorkerEvent) Commit(message Message) error {
+	_, err := w.consumer.CommitOffsets([]kafka.TopicPartition{{
+		Topic: &message.Topic,
+		Partition: message.Partition,
+		Offset: kafka.Offset(message.Offset + 1),
+	}})
+	return err
+}
+
+// About commit the offsets of a batch, the next offset to be read of each partition
+func (w *WorkerEvent) CommitBatch(messages []Message) error {
+	type partitionKey struct {
+		topic		string
+		partition	int32
+	}
+
+	next := make(map[partitionKey]int64)
+	for _, message := range messages {
+		key := partitionKey{message.Topic, message.Partition}
+		if message.Offset + 1 > next[key] {
+			next[key] = message.Offset + 1
+		}
+	}
+
+	topicPartitions := []kafka.TopicPartition{}
+	for key, offset := range next {
+		topic := key.topic
+		topicPartitions = append(topicPartitions, kafka.TopicPartition{
+			Topic: &topic,
+			Partition: key.partition,
+			Offset: kafka.Offset(offset),
+		})
+	}
+
+	_, err := w.consumer.CommitOffsets(topicPartitions)
+	return err
+}
+
+// About rewind the partition of a message to it, the message is read again
+func (w *WorkerEvent) Seek(message Message) error {
+	return w.consumer.Seek(kafka.TopicPartition{
+		Topic: &message.Topic,
+		Partition: message.Partition,
+		Offset: kafka.Offset(message.Offset),
+	}, 0)
+}
+
+// About pause the partitions assigned to the consumer
+func (w *WorkerEvent) Pause() error {
+	topicPartitions, err := w.consumer.Assignment()
+	if err != nil {
+		return err
+	}
+	return w.consumer.Pause(topicPartitions)
+}
+
+// About resume the partitions assigned to the consumer
+func (w *WorkerEvent) Resume() error {
+	topicPartitions, err := w.consumer.Assignment()
+	if err != nil {
+		return err
+	}
+	return w.consumer.Resume(topicPartitions)
+}
+
+// About close the consumer (leave the group)
+func (w *WorkerEvent) Close() {
+	childLogger.Info().Str("func","Close").Send()
+
+	err := w.consumer.Close()
+	if err != nil {
+		childLogger.Error().Err(err).Msg("error close consumer")
+	}
+	if w.producer != nil {
+		w.producer.Flush(5000)
+		w.producer.Close()
+	}
+}
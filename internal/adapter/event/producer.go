@@ -0,0 +1,103 @@
+package event
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	go_core_event "github.com/eliezerraj/go-core/event/kafka"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// headers of the error metadata of a dead-letter message
+const (
+	HeaderDlqError		= "dlq-error"
+	HeaderDlqReason		= "dlq-reason"
+	HeaderDlqTopic		= "dlq-original-topic"
+	HeaderDlqPartition	= "dlq-original-partition"
+	HeaderDlqOffset		= "dlq-original-offset"
+	HeaderDlqAttempts	= "dlq-attempts"
+	HeaderDlqFailedAt	= "dlq-failed-at"
+	HeaderDlqConsumer	= "dlq-consumer"
+)
+
+// About create a kafka producer of the dead-letter topic
+func newProducer(kafkaConfigurations *go_core_event.KafkaConfigurations) (*kafka.Producer, error) {
+	childLogger.Info().Str("func","newProducer").Send()
+
+	configMap := &kafka.ConfigMap{	"bootstrap.servers": brokersOf(kafkaConfigurations),
+									"security.protocol": kafkaConfigurations.Protocol,
+									"sasl.mechanisms": kafkaConfigurations.Mechanisms,
+									"sasl.username": kafkaConfigurations.Username,
+									"sasl.password": kafkaConfigurations.Password,
+									"client.id": kafkaConfigurations.Clientid,
+									"broker.address.family": "v4",
+									"acks": "all",
+									"enable.idempotence": true,
+								}
+
+	return kafka.NewProducer(configMap)
+}
+
+// About send a message to the dead-letter topic, with its original headers and the error metadata
+// Waits the delivery report, so the original offset is only committed when the message is safe
+func (w *WorkerEvent) DeadLetter(ctx context.Context, message Message, reason string, cause error, attempts int, consumer string) error {
+	childLogger.Info().Str("func","DeadLetter").Str("topic", message.Topic).Int32("partition", message.Partition).Int64("offset", message.Offset).Send()
+
+	//trace
+	span := tracerProvider.Span(ctx, "adapter.event.DeadLetter")
+	defer span.End()
+
+	if w.producer == nil {
+		return ErrNoDeadLetter
+	}
+
+	headers := []kafka.Header{}
+	for key, value := range message.Header {
+		headers = append(headers, kafka.Header{Key: key, Value: []byte(value)})
+	}
+	errorMsg := ""
+	if cause != nil {
+		errorMsg = cause.Error()
+	}
+	metadata := map[string]string{
+		HeaderDlqError: errorMsg,
+		HeaderDlqReason: reason,
+		HeaderDlqTopic: message.Topic,
+		HeaderDlqPartition: strconv.Itoa(int(message.Partition)),
+		HeaderDlqOffset: strconv.FormatInt(message.Offset, 10),
+		HeaderDlqAttempts: strconv.Itoa(attempts),
+		HeaderDlqFailedAt: time.Now().UTC().Format(time.RFC3339),
+		HeaderDlqConsumer: consumer,
+	}
+	for key, value := range metadata {
+		headers = append(headers, kafka.Header{Key: key, Value: []byte(value)})
+	}
+
+	var key []byte
+	if message.Key != "" {
+		key = []byte(message.Key)
+	}
+
+	deliveryChan := make(chan kafka.Event, 1)
+	err := w.producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &w.dlqTopic, Partition: kafka.PartitionAny},
+		Key: key,
+		Value: message.Payload,
+		Headers: headers,
+	}, deliveryChan)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case e := <-deliveryChan:
+		if m, ok := e.(*kafka.Message); ok && m.TopicPartition.Error != nil {
+			return m.TopicPartition.Error
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
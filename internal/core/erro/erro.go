@@ -2,6 +2,8 @@ package erro
 
 import (
 	"errors"
+	"fmt"
+	"time"
 )
 
 var (
@@ -13,4 +15,74 @@ var (
 	ErrServer		 	= errors.New("server identified error")
 	ErrHTTPForbiden		= errors.New("forbiden request")
 	ErrInvalid			= errors.New("invalid data")
-)
\ No newline at end of file
+	ErrKeyEncryption	= errors.New("signing key encryption key missing or invalid")
+)
+
+// kind of a delivery error
+var (
+	ErrRetryable		= errors.New("retryable delivery error")
+	ErrPermanent		= errors.New("permanent delivery error")
+	ErrThrottled		= errors.New("delivery throttled by receiver")
+	ErrEndpointGone		= errors.New("receiver endpoint gone")
+	ErrConfigMissing	= errors.New("webhook config missing")
+)
+
+// class of a delivery error, persisted in the webhook
+var classes = map[error]string{
+	ErrRetryable: 		"RETRYABLE",
+	ErrPermanent: 		"PERMANENT",
+	ErrThrottled: 		"THROTTLED",
+	ErrEndpointGone: 	"ENDPOINT-GONE",
+	ErrConfigMissing: 	"CONFIG-MISSING",
+}
+
+// DeliveryError is the error of a webhook delivery
+// errors.Is matches both the kind (ErrRetryable ...) and the cause
+type DeliveryError struct {
+	Kind		error
+	StatusCode	int
+	RetryAfter	time.Duration
+	Err			error
+}
+
+// About create a delivery error
+func NewDeliveryError(kind error, statusCode int, err error) *DeliveryError {
+	return &DeliveryError{
+		Kind: kind,
+		StatusCode: statusCode,
+		Err: err,
+	}
+}
+
+func (e *DeliveryError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s (status code %v): %s", e.Kind.Error(), e.StatusCode, e.Err.Error())
+	}
+	return fmt.Sprintf("%s (status code %v)", e.Kind.Error(), e.StatusCode)
+}
+
+func (e *DeliveryError) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+// About the class of a delivery error (empty when it is not a delivery error)
+func (e *DeliveryError) Class() string {
+	return classes[e.Kind]
+}
+
+// About the class of any error (empty when it is not a delivery error)
+func ClassOf(err error) string {
+	var deliveryError *DeliveryError
+	if errors.As(err, &deliveryError) {
+		return deliveryError.Class()
+	}
+	return ""
+}
+
+// About a delivery error can be sent again
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrRetryable) || errors.Is(err, ErrThrottled)
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+var childLogger = log.With().Str("component","go-worker-webhook").Str("package","internal.core.handler").Logger()
+
+// Receiver of an event and its role in the event (may be empty)
+type Receiver struct {
+	Receiver	string
+	Role		string
+}
+
+// EventHandler knows a domain event type, from the consumed payload to the body sent to each receiver
+type EventHandler interface {
+	// the event type (webhook type), e.g. TOPIC:PIX
+	Type() string
+	// decode the consumed payload
+	Decode(payload []byte) (any, error)
+	// validate the decoded event
+	Validate(event any) error
+	// the receivers to be notified
+	Receivers(event any) ([]Receiver, error)
+	// the key of the event (ordering by transaction), may be empty
+	Key(event any) string
+	// the id of the event (idempotency), may be empty to use the kafka coordinates
+	EventID(event any) string
+	// the body sent to the receiver
+	Body(event any, receiver Receiver) ([]byte, error)
+}
+
+// Registry of the event handlers by type
+type Registry struct {
+	mutex		sync.RWMutex
+	handlers	map[string]EventHandler
+}
+
+// About create a registry
+func NewRegistry(eventHandlers ...EventHandler) *Registry {
+	childLogger.Info().Str("func","NewRegistry").Send()
+
+	registry := &Registry{handlers: make(map[string]EventHandler)}
+	for _, eventHandler := range eventHandlers {
+		registry.Register(eventHandler)
+	}
+	return registry
+}
+
+// About register a handler (replaces the handler of the same type)
+func (r *Registry) Register(eventHandler EventHandler) {
+	childLogger.Info().Str("func","Register").Str("type", eventHandler.Type()).Send()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.handlers[eventHandler.Type()] = eventHandler
+}
+
+// About get the handler of a type
+func (r *Registry) Get(eventType string) (EventHandler, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	eventHandler, ok := r.handlers[eventType]
+	return eventHandler, ok
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-worker-webhook/internal/core/model"
+	"github.com/go-worker-webhook/internal/core/erro"
+	pkg_webhook "github.com/go-worker-webhook/pkg/webhook"
+)
+
+const TypePix = "TOPIC:PIX"
+
+// PixHandler notifies the payer and/or the payee of a PIX transaction
+type PixHandler struct {
+	pixConfig	*model.PixConfig
+}
+
+// About create the PIX handler
+func NewPixHandler(pixConfig *model.PixConfig) *PixHandler {
+	return &PixHandler{pixConfig: pixConfig}
+}
+
+func (h *PixHandler) Type() string {
+	return TypePix
+}
+
+// About decode a PIX transaction
+func (h *PixHandler) Decode(payload []byte) (any, error) {
+	pixTransaction := model.PixTransaction{}
+	if err := json.Unmarshal(payload, &pixTransaction); err != nil {
+		return nil, fmt.Errorf("%w: %v", erro.ErrUnmarshal, err)
+	}
+	return &pixTransaction, nil
+}
+
+// About validate a PIX transaction has at least one side
+func (h *PixHandler) Validate(event any) error {
+	pixTransaction, ok := event.(*model.PixTransaction)
+	if !ok {
+		return erro.ErrInvalid
+	}
+	if pixTransaction.AccountFrom.AccountID == "" && pixTransaction.AccountFrom.PersonID == "" &&
+		pixTransaction.AccountTo.AccountID == "" && pixTransaction.AccountTo.PersonID == "" {
+		return fmt.Errorf("%w: pix transaction without account", erro.ErrInvalid)
+	}
+	return nil
+}
+
+// About the receivers of a PIX transaction (payer, payee or both), by account or by person
+func (h *PixHandler) Receivers(event any) ([]Receiver, error) {
+	pixTransaction, ok := event.(*model.PixTransaction)
+	if !ok {
+		return nil, erro.ErrInvalid
+	}
+
+	roles := map[string]model.Account{}
+	switch h.pixConfig.ReceiverMode {
+	case "PAYEE":
+		roles[pkg_webhook.RolePayee] = pixTransaction.AccountTo
+	case "BOTH":
+		roles[pkg_webhook.RolePayer] = pixTransaction.AccountFrom
+		roles[pkg_webhook.RolePayee] = pixTransaction.AccountTo
+	default:
+		roles[pkg_webhook.RolePayer] = pixTransaction.AccountFrom
+	}
+
+	list_receiver := []Receiver{}
+	for _, role := range []string{pkg_webhook.RolePayer, pkg_webhook.RolePayee} {
+		account, ok := roles[role]
+		if !ok {
+			continue
+		}
+
+		var receiver string
+		switch h.pixConfig.ReceiverKey {
+		case "PERSON":
+			if account.PersonID != "" {
+				receiver = "PERSON:" + account.PersonID
+			}
+		default:
+			if account.AccountID != "" {
+				receiver = "ACCOUNT:" + account.AccountID
+			}
+		}
+		if receiver == "" {
+			childLogger.Info().Str("role", role).Msg("NO RECEIVER FOR THE ROLE !!!")
+			continue
+		}
+
+		list_receiver = append(list_receiver, Receiver{Receiver: receiver, Role: role})
+	}
+
+	return list_receiver, nil
+}
+
+// About the key of a PIX transaction
+func (h *PixHandler) Key(event any) string {
+	pixTransaction, ok := event.(*model.PixTransaction)
+	if !ok {
+		return ""
+	}
+	return pixTransaction.TransactionId
+}
+
+// About the id of a PIX event, a transaction notifies each status once
+func (h *PixHandler) EventID(event any) string {
+	pixTransaction, ok := event.(*model.PixTransaction)
+	if !ok || pixTransaction.TransactionId == "" {
+		return ""
+	}
+	return pixTransaction.TransactionId + ":" + pixTransaction.Status
+}
+
+// About the PIX transaction with the role of the receiver
+func (h *PixHandler) Body(event any, receiver Receiver) ([]byte, error) {
+	pixTransaction, ok := event.(*model.PixTransaction)
+	if !ok {
+		return nil, erro.ErrInvalid
+	}
+
+	body := *pixTransaction
+	body.Role = receiver.Role
+	return json.Marshal(body)
+}
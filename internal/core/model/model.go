@@ -5,6 +5,7 @@ import (
 	go_core_pg "github.com/eliezerraj/go-core/database/pg"
 	go_core_observ "github.com/eliezerraj/go-core/observability"
 	go_core_event "github.com/eliezerraj/go-core/event/kafka" 
+	pkg_webhook "github.com/go-worker-webhook/pkg/webhook"
 )
 
 type AppServer struct {
@@ -13,6 +14,25 @@ type AppServer struct {
 	DatabaseConfig		*go_core_pg.DatabaseConfig  `json:"database"`
 	KafkaConfigurations	*go_core_event.KafkaConfigurations  `json:"kafka_configurations"`
 	Topics 				[]string					`json:"topics"`	
+	TopicRouting		*TopicRouting				`json:"topic_routing"`
+	ConsumerConfig		*ConsumerConfig				`json:"consumer_config"`
+	BackpressureConfig	*BackpressureConfig			`json:"backpressure_config"`
+	DispatcherConfig	*DispatcherConfig			`json:"dispatcher_config"`
+	RetryPolicy			*RetryPolicy				`json:"retry_policy"`
+	Server				*Server						`json:"server"`
+	SigningConfig		*SigningConfig				`json:"signing_config"`
+	BreakerConfig		*BreakerConfig				`json:"breaker_config"`
+	PixConfig			*PixConfig					`json:"pix_config"`
+}
+
+type Server struct {
+	Port 			int `json:"port"`
+	ReadTimeout		int `json:"readTimeout"`
+	WriteTimeout	int `json:"writeTimeout"`
+	IdleTimeout		int `json:"idleTimeout"`
+	CtxTimeout		int `json:"ctxTimeout"`
+	AdminPort		int `json:"admin_port"`
+	AdminTokens		map[string]string `json:"-"`
 }
 
 type InfoPod struct {
@@ -24,44 +44,157 @@ type InfoPod struct {
 	IsAZ				bool   	`json:"is_az"`
 	Env					string `json:"enviroment,omitempty"`
 	AccountID			string `json:"account_id,omitempty"`
+	Hostname			string `json:"hostname,omitempty"`
+}
+
+type DispatcherConfig struct {
+	WorkerCount			int		`json:"worker_count"`
+	QueueDepth			int		`json:"queue_depth"`
+	BatchSize			int		`json:"batch_size"`
+	PollInterval		int		`json:"poll_interval_ms"`
+	LeaseTime			int		`json:"lease_time_sec"`
+	ThrottleTime		int		`json:"throttle_time_ms"`
+}
+
+type RetryPolicy struct {
+	MaxAttempts			int		`json:"max_attempts,omitempty"`
+	BaseDelay			int		`json:"base_delay_ms,omitempty"`
+	MaxDelay			int		`json:"max_delay_ms,omitempty"`
+}
+
+type ConsumerConfig struct {
+	DlqTopic			string	`json:"dlq_topic,omitempty"`
+	DlqSkip				bool	`json:"dlq_skip"`
+	MaxRetry			int		`json:"max_retry"`
+	RetryDelay			int		`json:"retry_delay_ms"`
+	BatchSize			int		`json:"batch_size"`
+	BatchTimeout		int		`json:"batch_timeout_ms"`
+}
+
+type BackpressureConfig struct {
+	HighWater			int		`json:"high_water"`
+	LowWater			int		`json:"low_water"`
+	CheckInterval		int		`json:"check_interval_ms"`
+}
+
+type TopicRouting struct {
+	Routes				map[string]string	`json:"routes"`
+	TypeHeader			string	`json:"type_header,omitempty"`
+}
+
+type PixConfig struct {
+	ReceiverMode		string	`json:"receiver_mode"`
+	ReceiverKey			string	`json:"receiver_key"`
+}
+
+type BreakerConfig struct {
+	FailureThreshold	int		`json:"failure_threshold"`
+	OpenTime			int		`json:"open_time_ms"`
+}
+
+type CircuitBreaker struct {
+	Host				string		`json:"host"`
+	State				string		`json:"state"`
+	Failures			int			`json:"failures"`
+	OpenedAt			*time.Time	`json:"opened_at,omitempty"`
+	ProbeAt				*time.Time	`json:"probe_at,omitempty"`
+	UpdatedAt			time.Time	`json:"updated_at"`
+}
+
+type RateLimit struct {
+	RPS					float64	`json:"rate_limit_rps,omitempty"`
+	Burst				int		`json:"rate_limit_burst,omitempty"`
+	MaxInFlight			int		`json:"max_in_flight,omitempty"`
+}
+
+type ResponseRule struct {
+	Status				string	`json:"status"`
+	Action				string	`json:"action"`
 }
 
-type StepProcess struct {
-	Name		string  	`json:"step_process,omitempty"`
-	ProcessedAt	time.Time 	`json:"processed_at,omitempty"`
+type SigningConfig struct {
+	KeyPublishTime		int		`json:"key_publish_time_sec"`
+	KeyCacheTime		int		`json:"key_cache_time_sec"`
+	JWKSMaxAge			int		`json:"jwks_max_age_sec"`
+	EncryptionKey		[]byte	`json:"-"`
 }
 
+type SigningKey struct {
+	KeyID			string		`json:"kid,omitempty"`
+	Algorithm		string		`json:"algorithm,omitempty"`
+	PrivateKey		[]byte		`json:"-"`
+	Encrypted		bool		`json:"-"`
+	PublicKey		[]byte		`json:"public_key,omitempty"`
+	CreatedAt		time.Time 	`json:"created_at,omitempty"`
+	ActiveAt		time.Time 	`json:"active_at,omitempty"`
+	RetiredAt		*time.Time 	`json:"retired_at,omitempty"`
+}
+
+// the event types are public (pkg/webhook) so the receivers can decode them
+type StepProcess = pkg_webhook.StepProcess
+
 type WebHook struct {
 	ID				int			`json:"id,omitempty"`
+	ConfigID		int			`json:"webhook_config_id,omitempty"`
 	Receiver		string 		`json:"receiver,omitempty"`
 	Host			string 		`json:"host,omitempty"`
 	Url				string 		`json:"url,omitempty"`
 	Method			string 		`json:"method,omitempty"`
 	Topic			string 		`json:"topic,omitempty"`
 	Type			string 		`json:"type,omitempty"`	
+	EventKey		string		`json:"event_key,omitempty"`
+	EventID			string		`json:"event_id,omitempty"`
+	KafkaPartition	int32		`json:"kafka_partition,omitempty"`
+	KafkaOffset		int64		`json:"kafka_offset,omitempty"`
+	KafkaKey		string		`json:"kafka_key,omitempty"`
 	Payload			[]byte	 	`json:"payload,omitempty"`
 	Status			string  	`json:"status,omitempty"`
+	ClaimedBy		string  	`json:"claimed_by,omitempty"`
+	LeaseExpiresAt	*time.Time 	`json:"lease_expires_at,omitempty"`
+	Attempts		int			`json:"attempts,omitempty"`
+	NextAttemptAt	*time.Time 	`json:"next_attempt_at,omitempty"`
+	RetryPolicy		*RetryPolicy `json:"retry_policy,omitempty"`
+	RateLimit		*RateLimit	`json:"rate_limit,omitempty"`
+	Weight			int			`json:"weight,omitempty"`
+	OrderingMode	string		`json:"ordering_mode,omitempty"`
+	OrderingKey		string		`json:"ordering_key,omitempty"`
+	SequenceNo		int64		`json:"sequence_no,omitempty"`
+	LastError		string		`json:"last_error,omitempty"`
+	ErrorClass		string		`json:"error_class,omitempty"`
+	ResponseRules	[]ResponseRule	`json:"response_rules,omitempty"`
+	SignatureMode	string		`json:"signature_mode,omitempty"`
+	SigningSecret	string		`json:"-"`
+	SigningSecretPrevious	string	`json:"-"`
+	SigningSecretPreviousExpiresAt	*time.Time	`json:"-"`
+	ReplayedBy		string		`json:"replayed_by,omitempty"`
+	ReplayedAt		*time.Time 	`json:"replayed_at,omitempty"`
 	CreatedAt		time.Time 	`json:"created_at,omitempty"`
 	UpdatedAt		*time.Time 	`json:"updated_at,omitempty"`
 }
 
-type Account struct {
+type DeliveryAttempt struct {
 	ID				int			`json:"id,omitempty"`
-	AccountID		string		`json:"account_id,omitempty"`
-	PersonID		string  	`json:"person_id,omitempty"`
+	WebHookID		int			`json:"webhook_transaction_id,omitempty"`
+	Attempt			int			`json:"attempt,omitempty"`
+	RequestHeaders	map[string]string `json:"request_headers,omitempty"`
+	ResponseStatus	int			`json:"response_status"`
+	ResponseBody	string		`json:"response_body,omitempty"`
+	Latency			int64		`json:"latency_ms"`
+	ErrorClass		string		`json:"error_class,omitempty"`
+	Error			string		`json:"error,omitempty"`
+	PodName			string		`json:"pod_name,omitempty"`
+	CreatedAt		time.Time 	`json:"created_at,omitempty"`
 }
 
-type PixTransaction struct {
+type ReplayFilter struct {
 	ID				int			`json:"id,omitempty"`
-	TransactionId	string 		`json:"transaction_id,omitempty"`
-	TransactionAt	time.Time 	`json:"transaction_at,omitempty"`	
-	RequestId		string 		`json:"request_id,omitempty"`
-	AccountFrom		Account		`json:"account_from,omitempty"`
-	AccountTo		Account		`json:"account_to,omitempty"`
-	Status			string  	`json:"status,omitempty"`
-	Currency		string 		`json:"currency,omitempty"`
-	Amount			float64 	`json:"amount,omitempty"`	
-	StepProcess		*[]StepProcess	`json:"step_process,omitempty"`
-	CreatedAt		time.Time 	`json:"created_at,omitempty"`
-	UpdatedAt		*time.Time 	`json:"updated_at,omitempty"`
-}
\ No newline at end of file
+	Receiver		string 		`json:"receiver,omitempty"`
+	ErrorClass		string 		`json:"error_class,omitempty"`
+	From			*time.Time 	`json:"from,omitempty"`
+	To				*time.Time 	`json:"to,omitempty"`
+	ReplayedBy		string 		`json:"-"`
+}
+
+type Account = pkg_webhook.Account
+
+type PixTransaction = pkg_webhook.PixTransaction
\ No newline at end of file
@@ -0,0 +1,149 @@
+package service
+
+import(
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/go-worker-webhook/internal/core/model"
+	"github.com/go-worker-webhook/internal/core/erro"
+)
+
+const (
+	BreakerClosed	= "CLOSED"
+	BreakerOpen		= "OPEN"
+	BreakerHalfOpen	= "HALF-OPEN"
+)
+
+// the value of the state in the metrics
+var breakerStates = map[string]int64{
+	BreakerClosed: 0,
+	BreakerHalfOpen: 1,
+	BreakerOpen: 2,
+}
+
+// About check a delivery to the host is allowed by its circuit breaker
+// When not allowed, returns when the host will be probed again
+func (s *WorkerService) AllowDelivery(host string) (time.Time, bool){
+	s.breakerMutex.Lock()
+	defer s.breakerMutex.Unlock()
+
+	breaker, ok := s.breakers[host]
+	if !ok {
+		return time.Time{}, true
+	}
+
+	openTime := time.Duration(s.breakerConfig.OpenTime) * time.Millisecond
+	now := time.Now()
+
+	switch breaker.State {
+	case BreakerOpen:
+		probeAt := breaker.OpenedAt.Add(openTime)
+		if now.Before(probeAt) {
+			return probeAt, false
+		}
+		// let a single request probe the host
+		breaker.State = BreakerHalfOpen
+		breaker.ProbeAt = &now
+		childLogger.Warn().Str("host", host).Msg("CIRCUIT BREAKER HALF-OPEN !!!")
+		return time.Time{}, true
+	case BreakerHalfOpen:
+		// a probe is in flight (a lost probe is replaced after the open time)
+		if breaker.ProbeAt != nil && now.Before(breaker.ProbeAt.Add(openTime)) {
+			return breaker.ProbeAt.Add(openTime), false
+		}
+		breaker.ProbeAt = &now
+		return time.Time{}, true
+	default:
+		return time.Time{}, true
+	}
+}
+
+// About a delivery error caused by the host, no answer (timeout, connection) or 5xx
+// A 4xx is the answer of a live host, even when the rules retry it
+func isHostFailure(deliveryErr error) bool {
+	var deliveryError *erro.DeliveryError
+	if !errors.As(deliveryErr, &deliveryError) || errors.Is(deliveryErr, erro.ErrThrottled) {
+		return false
+	}
+	return deliveryError.StatusCode == 0 || deliveryError.StatusCode >= 500
+}
+
+// About record the result of a delivery in the circuit breaker of the host
+// Only the failures of the host (timeout, connection, 5xx) count, any answer of a live host closes it
+func (s *WorkerService) recordDelivery(host string, deliveryErr error){
+	// a call cancelled (shutdown) says nothing about the host
+	if errors.Is(deliveryErr, context.Canceled) {
+		return
+	}
+
+	s.breakerMutex.Lock()
+	defer s.breakerMutex.Unlock()
+
+	breaker, ok := s.breakers[host]
+	if !ok {
+		breaker = &model.CircuitBreaker{Host: host, State: BreakerClosed}
+		s.breakers[host] = breaker
+	}
+
+	now := time.Now()
+	breaker.UpdatedAt = now
+
+	if !isHostFailure(deliveryErr) {
+		if breaker.State != BreakerClosed {
+			childLogger.Info().Str("host", host).Msg("CIRCUIT BREAKER CLOSED !!!")
+		}
+		breaker.State = BreakerClosed
+		breaker.Failures = 0
+		breaker.OpenedAt = nil
+		breaker.ProbeAt = nil
+		return
+	}
+
+	breaker.Failures = breaker.Failures + 1
+	if breaker.State == BreakerHalfOpen || 
+		(breaker.State == BreakerClosed && breaker.Failures >= s.breakerConfig.FailureThreshold) {
+		breaker.State = BreakerOpen
+		breaker.OpenedAt = &now
+		breaker.ProbeAt = nil
+		childLogger.Error().Str("host", host).Int("failures", breaker.Failures).Msg("CIRCUIT BREAKER OPEN !!!")
+	}
+}
+
+// About list the circuit breakers
+func (s *WorkerService) GetCircuitBreakers(ctx context.Context) []model.CircuitBreaker{
+	childLogger.Debug().Str("func","GetCircuitBreakers").Send()
+
+	s.breakerMutex.Lock()
+	defer s.breakerMutex.Unlock()
+
+	list_breaker := []model.CircuitBreaker{}
+	for _, breaker := range s.breakers {
+		list_breaker = append(list_breaker, *breaker)
+	}
+	sort.Slice(list_breaker, func(i, j int) bool { return list_breaker[i].Host < list_breaker[j].Host })
+
+	return list_breaker
+}
+
+// About expose the state of the circuit breakers (0 closed, 1 half-open, 2 open)
+func (s *WorkerService) registerBreakerMetrics() {
+	meter := otel.Meter("go-worker-webhook")
+
+	_, err := meter.Int64ObservableGauge("webhook.circuit_breaker.state",
+		metric.WithDescription("circuit breaker state per host (0 closed, 1 half-open, 2 open)"),
+		metric.WithInt64Callback(func(ctx context.Context, observer metric.Int64Observer) error {
+			for _, breaker := range s.GetCircuitBreakers(ctx) {
+				observer.Observe(breakerStates[breaker.State], metric.WithAttributes(attribute.String("host", breaker.Host)))
+			}
+			return nil
+		}))
+	if err != nil {
+		childLogger.Error().Err(err).Msg("error register circuit breaker metrics")
+	}
+}
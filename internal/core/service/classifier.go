@@ -0,0 +1,117 @@
+package service
+
+import(
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-worker-webhook/internal/adapter/client"
+	"github.com/go-worker-webhook/internal/core/model"
+	"github.com/go-worker-webhook/internal/core/erro"
+)
+
+// the action taken for a receiver response
+const (
+	ActionSuccess	= "SUCCESS"		// delivered
+	ActionRetry		= "RETRY"		// send again (backoff)
+	ActionThrottled	= "THROTTLED"	// send again, the receiver asked to slow down
+	ActionPermanent	= "PERMANENT"	// dead-letter, no retry
+	ActionDisable	= "DISABLE"		// dead-letter and disable the webhook_config (endpoint gone)
+)
+
+// kind of delivery error of each action
+var actionKinds = map[string]error{
+	ActionRetry: 		erro.ErrRetryable,
+	ActionThrottled: 	erro.ErrThrottled,
+	ActionPermanent: 	erro.ErrPermanent,
+	ActionDisable: 		erro.ErrEndpointGone,
+}
+
+// About check the response rules of a webhook_config, the actions are case insensitive
+// An unknown action is rejected, otherwise the response would fall back to a retry (even a 2xx)
+func validResponseRules(responseRules []model.ResponseRule) ([]model.ResponseRule, error) {
+	res_rule_list := []model.ResponseRule{}
+	for _, rule := range responseRules {
+		rule.Action = strings.ToUpper(strings.TrimSpace(rule.Action))
+		if _, ok := actionKinds[rule.Action]; !ok && rule.Action != ActionSuccess {
+			return nil, fmt.Errorf("%w: response rule %v with unknown action %q", erro.ErrInvalid, rule.Status, rule.Action)
+		}
+		res_rule_list = append(res_rule_list, rule)
+	}
+	return res_rule_list, nil
+}
+
+// About match a rule status against the status code
+// A rule status is a status code (409), a class (4xx) or timeout (no response)
+func matchStatus(ruleStatus string, statusCode int) bool {
+	switch {
+	case ruleStatus == "timeout":
+		return statusCode == 0
+	case len(ruleStatus) == 3 && ruleStatus[1:] == "xx":
+		return statusCode > 0 && strconv.Itoa(statusCode / 100) == ruleStatus[:1]
+	default:
+		return ruleStatus == strconv.Itoa(statusCode)
+	}
+}
+
+// About find the action of the receiver response with the rules of the webhook_config
+// An exact status code rule wins over a class rule, without a matching rule
+// 2xx is success, 429 (and 503 with Retry-After) is throttled and anything else is retried
+func responseAction(statusCode int, retryAfter time.Duration, responseRules []model.ResponseRule) string {
+	for _, exact := range []bool{true, false} {
+		for _, rule := range responseRules {
+			if (rule.Status == strconv.Itoa(statusCode)) != exact {
+				continue
+			}
+			if matchStatus(rule.Status, statusCode) {
+				return rule.Action
+			}
+		}
+	}
+
+	switch {
+	case statusCode >= 200 && statusCode < 300:
+		return ActionSuccess
+	case statusCode == http.StatusTooManyRequests:
+		return ActionThrottled
+	case statusCode == http.StatusServiceUnavailable && retryAfter > 0:
+		return ActionThrottled
+	default:
+		return ActionRetry
+	}
+}
+
+// About classify the result of the http call, nil means delivered
+func classifyResponse(webHookResponse *client.WebHookResponse, err error, responseRules []model.ResponseRule) error {
+	// already classified (ex: config missing before the call)
+	var deliveryError *erro.DeliveryError
+	if errors.As(err, &deliveryError) {
+		return deliveryError
+	}
+
+	statusCode := webHookResponse.StatusCode
+	retryAfter := parseRetryAfter(webHookResponse.Header, time.Now())
+
+	action := responseAction(statusCode, retryAfter, responseRules)
+	if action == ActionSuccess {
+		return nil
+	}
+
+	kind, ok := actionKinds[action]
+	if !ok {
+		kind = erro.ErrRetryable
+	}
+	if err == nil {
+		err = errors.New(http.StatusText(statusCode))
+	}
+
+	deliveryError = erro.NewDeliveryError(kind, statusCode, err)
+	if kind == erro.ErrThrottled || kind == erro.ErrRetryable {
+		deliveryError.RetryAfter = retryAfter
+	}
+
+	return deliveryError
+}
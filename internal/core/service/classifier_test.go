@@ -0,0 +1,67 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-worker-webhook/internal/adapter/client"
+	"github.com/go-worker-webhook/internal/core/erro"
+	"github.com/go-worker-webhook/internal/core/model"
+)
+
+// The actions of the response rules are case insensitive, an unknown one is rejected
+func TestValidResponseRules(t *testing.T) {
+	tests := []struct {
+		name		string
+		rules		[]model.ResponseRule
+		statusCode	int
+		want		error
+		err			error
+	}{
+		{
+			name: "no rules",
+			statusCode: http.StatusOK,
+		},
+		{
+			name: "lowercase success",
+			rules: []model.ResponseRule{{Status: "2xx", Action: "success"}},
+			statusCode: http.StatusOK,
+		},
+		{
+			name: "lowercase permanent",
+			rules: []model.ResponseRule{{Status: "409", Action: " permanent "}},
+			statusCode: http.StatusConflict,
+			want: erro.ErrPermanent,
+		},
+		{
+			name: "typo in the action",
+			rules: []model.ResponseRule{{Status: "2xx", Action: "SUCESS"}},
+			statusCode: http.StatusOK,
+			err: erro.ErrInvalid,
+		},
+		{
+			name: "empty action",
+			rules: []model.ResponseRule{{Status: "410"}},
+			statusCode: http.StatusGone,
+			err: erro.ErrInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res_rule_list, err := validResponseRules(tt.rules)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+
+			deliveryErr := classifyResponse(&client.WebHookResponse{StatusCode: tt.statusCode}, nil, res_rule_list)
+			if !errors.Is(deliveryErr, tt.want) || (tt.want == nil && deliveryErr != nil) {
+				t.Fatalf("got %v, want %v", deliveryErr, tt.want)
+			}
+		})
+	}
+}
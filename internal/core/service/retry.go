@@ -0,0 +1,69 @@
+package service
+
+import(
+	"time"
+	"errors"
+	"math/rand/v2"
+
+	"github.com/go-worker-webhook/internal/core/model"
+	"github.com/go-worker-webhook/internal/core/erro"
+)
+
+// About merge the retry policy of the webhook_config with the default one
+func (s *WorkerService) retryPolicyOf(webhook *model.WebHook) model.RetryPolicy {
+	retryPolicy := *s.retryPolicy
+
+	if webhook.RetryPolicy != nil {
+		if webhook.RetryPolicy.MaxAttempts > 0 {
+			retryPolicy.MaxAttempts = webhook.RetryPolicy.MaxAttempts
+		}
+		if webhook.RetryPolicy.BaseDelay > 0 {
+			retryPolicy.BaseDelay = webhook.RetryPolicy.BaseDelay
+		}
+		if webhook.RetryPolicy.MaxDelay > 0 {
+			retryPolicy.MaxDelay = webhook.RetryPolicy.MaxDelay
+		}
+	}
+
+	return retryPolicy
+}
+
+// About calc when the webhook must be sent again, nil when the attempts are exhausted
+// The Retry-After of the receiver wins over the backoff
+func (s *WorkerService) nextAttemptAt(webhook *model.WebHook, deliveryErr error) *time.Time {
+	retryPolicy := s.retryPolicyOf(webhook)
+
+	if webhook.Attempts >= retryPolicy.MaxAttempts {
+		childLogger.Info().Int("webhook", webhook.ID).Int("attempts", webhook.Attempts).Msg("RETRY ATTEMPTS EXHAUSTED !!!")
+		return nil
+	}
+
+	var deliveryError *erro.DeliveryError
+	if errors.As(deliveryErr, &deliveryError) && deliveryError.RetryAfter > 0 {
+		next := time.Now().Add(deliveryError.RetryAfter)
+		return &next
+	}
+
+	next := time.Now().Add(backoff(retryPolicy, webhook.Attempts))
+	return &next
+}
+
+// About exponential backoff (base * 2^(attempt-1)) capped at max delay, with jitter in [delay/2, delay]
+func backoff(retryPolicy model.RetryPolicy, attempt int) time.Duration {
+	baseDelay := time.Duration(retryPolicy.BaseDelay) * time.Millisecond
+	maxDelay := time.Duration(retryPolicy.MaxDelay) * time.Millisecond
+
+	delay := baseDelay
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay = delay * 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int64N(int64(half) + 1))
+}
@@ -0,0 +1,35 @@
+package service
+
+import(
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// max time a Retry-After is honored
+const maxRetryAfter = 24 * time.Hour
+
+// About parse the Retry-After header, in seconds (120) or http-date (Wed, 21 Oct 2015 07:28:00 GMT)
+// 0 means no (or invalid) Retry-After
+func parseRetryAfter(header http.Header, now time.Time) time.Duration {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+
+	var retryAfter time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		retryAfter = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		retryAfter = date.Sub(now)
+	}
+
+	if retryAfter < 0 {
+		return 0
+	}
+	if retryAfter > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return retryAfter
+}
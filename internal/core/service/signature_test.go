@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-worker-webhook/internal/core/model"
+	pkg_webhook "github.com/go-worker-webhook/pkg/webhook"
+)
+
+func newTestSecret(t *testing.T) string {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return pkg_webhook.SecretPrefix + base64.StdEncoding.EncodeToString(key)
+}
+
+// About a service with the signing keys already cached (no database)
+func newTestSigningService(t *testing.T, keys ...model.SigningKey) *WorkerService {
+	t.Helper()
+	return &WorkerService{
+		signingConfig: &model.SigningConfig{KeyCacheTime: 3600},
+		signingKeys: &keys,
+		signingKeysAt: time.Now(),
+	}
+}
+
+func newTestSigningKey(t *testing.T, keyID string, retired bool) model.SigningKey {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingKey := model.SigningKey{KeyID: keyID, Algorithm: "ED25519", PrivateKey: privateKey, PublicKey: publicKey}
+	if retired {
+		retiredAt := time.Now()
+		signingKey.RetiredAt = &retiredAt
+	}
+	return signingKey
+}
+
+// The webhooks signed by the worker are verified by the receiver SDK
+func TestSignerOfVerifiedBySDK(t *testing.T) {
+	secret, previousSecret := newTestSecret(t), newTestSecret(t)
+	overlap := time.Now().Add(time.Hour)
+	expired := time.Now().Add(-time.Hour)
+
+	// the active key (kid-2) and a retired one still published (kid-1)
+	workerService := newTestSigningService(t, newTestSigningKey(t, "kid-2", false), newTestSigningKey(t, "kid-1", true))
+	jwks, err := workerService.GetJWKS(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hmacVerifier := func(secrets ...string) func() (*pkg_webhook.Verifier, error) {
+		return func() (*pkg_webhook.Verifier, error) { return pkg_webhook.NewVerifier(secrets...) }
+	}
+	jwksVerifier := func(keys ...pkg_webhook.JWK) func() (*pkg_webhook.Verifier, error) {
+		return func() (*pkg_webhook.Verifier, error) { return pkg_webhook.NewJWKSVerifier(pkg_webhook.JWKS{Keys: keys}) }
+	}
+
+	body := []byte(`{"transactionId":"TX-001","status":"PAID"}`)
+
+	tests := []struct {
+		name		string
+		webhook		model.WebHook
+		verifier	func() (*pkg_webhook.Verifier, error)
+		timestamp	time.Time
+		body		[]byte
+		signatures	int
+		err			error
+	}{
+		{
+			name: "hmac v1",
+			webhook: model.WebHook{SignatureMode: "HMAC", SigningSecret: secret},
+			verifier: hmacVerifier(secret),
+			timestamp: time.Now(),
+			body: body,
+			signatures: 1,
+		},
+		{
+			name: "hmac v1 tampered body",
+			webhook: model.WebHook{SignatureMode: "HMAC", SigningSecret: secret},
+			verifier: hmacVerifier(secret),
+			timestamp: time.Now(),
+			body: []byte(`{"transactionId":"TX-001","status":"REFUNDED"}`),
+			signatures: 1,
+			err: pkg_webhook.ErrInvalidSignature,
+		},
+		{
+			name: "hmac v1 timestamp outside tolerance",
+			webhook: model.WebHook{SignatureMode: "HMAC", SigningSecret: secret},
+			verifier: hmacVerifier(secret),
+			timestamp: time.Now().Add(-pkg_webhook.DefaultTolerance - time.Minute),
+			body: body,
+			signatures: 1,
+			err: pkg_webhook.ErrTimestampTooOld,
+		},
+		{
+			name: "hmac v1 rotation, receiver still with the previous secret",
+			webhook: model.WebHook{SignatureMode: "HMAC", SigningSecret: secret, SigningSecretPrevious: previousSecret, SigningSecretPreviousExpiresAt: &overlap},
+			verifier: hmacVerifier(previousSecret),
+			timestamp: time.Now(),
+			body: body,
+			signatures: 2,
+		},
+		{
+			name: "hmac v1 rotation, receiver with the new secret",
+			webhook: model.WebHook{SignatureMode: "HMAC", SigningSecret: secret, SigningSecretPrevious: previousSecret, SigningSecretPreviousExpiresAt: &overlap},
+			verifier: hmacVerifier(secret),
+			timestamp: time.Now(),
+			body: body,
+			signatures: 2,
+		},
+		{
+			name: "hmac v1 rotation overlap expired",
+			webhook: model.WebHook{SignatureMode: "HMAC", SigningSecret: secret, SigningSecretPrevious: previousSecret, SigningSecretPreviousExpiresAt: &expired},
+			verifier: hmacVerifier(previousSecret),
+			timestamp: time.Now(),
+			body: body,
+			signatures: 1,
+			err: pkg_webhook.ErrInvalidSignature,
+		},
+		{
+			name: "ed25519 v1a with the published JWKS",
+			webhook: model.WebHook{SignatureMode: "ED25519"},
+			verifier: jwksVerifier(jwks.Keys...),
+			timestamp: time.Now(),
+			body: body,
+			signatures: 1,
+		},
+		{
+			name: "ed25519 v1a only the retired key published",
+			webhook: model.WebHook{SignatureMode: "ED25519"},
+			verifier: jwksVerifier(jwks.Keys[1]),
+			timestamp: time.Now(),
+			body: body,
+			signatures: 1,
+			err: pkg_webhook.ErrInvalidSignature,
+		},
+		{
+			name: "ed25519 v1a tampered body",
+			webhook: model.WebHook{SignatureMode: "ED25519"},
+			verifier: jwksVerifier(jwks.Keys...),
+			timestamp: time.Now(),
+			body: []byte(`{}`),
+			signatures: 1,
+			err: pkg_webhook.ErrInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := workerService.signerOf(context.Background(), &tt.webhook)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			header := http.Header{}
+			for key, value := range signer.Headers("123", tt.timestamp, body) {
+				header.Set(key, value)
+			}
+			if got := len(strings.Fields(header.Get(pkg_webhook.HeaderSignature))); got != tt.signatures {
+				t.Fatalf("got %v signatures, want %v", got, tt.signatures)
+			}
+			if tt.webhook.SignatureMode == "ED25519" && header.Get(pkg_webhook.HeaderKeyID) != "kid-2" {
+				t.Fatalf("got kid %q, want kid-2", header.Get(pkg_webhook.HeaderKeyID))
+			}
+
+			verifier, err := tt.verifier()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = verifier.Verify(header, tt.body)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+// A rotated key is published at once but the previous key signs until the rotated one is active
+func TestActiveSigningKey(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+
+	key := func(keyID string, activeAt time.Time, retiredAt *time.Time) model.SigningKey {
+		signingKey := newTestSigningKey(t, keyID, false)
+		signingKey.ActiveAt = activeAt
+		signingKey.RetiredAt = retiredAt
+		return signingKey
+	}
+
+	tests := []struct {
+		name	string
+		keys	[]model.SigningKey
+		kid		string
+	}{
+		{
+			name: "single key",
+			keys: []model.SigningKey{key("kid-1", past, nil)},
+			kid: "kid-1",
+		},
+		{
+			name: "rotated key not active yet",
+			keys: []model.SigningKey{key("kid-2", future, nil), key("kid-1", past, &future)},
+			kid: "kid-1",
+		},
+		{
+			name: "rotated key active",
+			keys: []model.SigningKey{key("kid-2", past, nil), key("kid-1", past.Add(-time.Hour), &past)},
+			kid: "kid-2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workerService := newTestSigningService(t, tt.keys...)
+
+			res_key, err := workerService.activeSigningKey(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res_key.KeyID != tt.kid {
+				t.Fatalf("got kid %v, want %v", res_key.KeyID, tt.kid)
+			}
+
+			// both keys are published during the rotation
+			jwks, err := workerService.GetJWKS(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(jwks.Keys) != len(tt.keys) {
+				t.Fatalf("got %v published keys, want %v", len(jwks.Keys), len(tt.keys))
+			}
+		})
+	}
+}
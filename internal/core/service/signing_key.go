@@ -0,0 +1,258 @@
+package service
+
+import(
+	"time"
+	"context"
+	"fmt"
+	"errors"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/ed25519"
+
+	"github.com/google/uuid"
+
+	"github.com/go-worker-webhook/internal/core/model"
+	"github.com/go-worker-webhook/internal/core/erro"
+	pkg_webhook "github.com/go-worker-webhook/pkg/webhook"
+)
+
+// About get the published signing keys, cached for KeyCacheTime
+func (s *WorkerService) getSigningKeys(ctx context.Context) (*[]model.SigningKey, error){
+	childLogger.Debug().Str("func","getSigningKeys").Send()
+
+	s.keyMutex.Lock()
+	defer s.keyMutex.Unlock()
+
+	if s.signingKeys != nil && time.Since(s.signingKeysAt) < time.Duration(s.signingConfig.KeyCacheTime) * time.Second {
+		return s.signingKeys, nil
+	}
+
+	res_key_list, err := s.workerRepository.GetSigningKeys(ctx, s.signingConfig.KeyPublishTime)
+	if err != nil {
+		return nil, err
+	}
+
+	// the private keys are stored encrypted (the keys of the first version are not)
+	for i := range *res_key_list {
+		if !(*res_key_list)[i].Encrypted {
+			continue
+		}
+		(*res_key_list)[i].PrivateKey, err = s.openPrivateKey((*res_key_list)[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.signingKeys = res_key_list
+	s.signingKeysAt = time.Now()
+
+	return res_key_list, nil
+}
+
+// About get the active signing key (the newest one between its active_at and its retired_at)
+// A rotated key is published before it is active, the previous key signs until then
+func (s *WorkerService) activeSigningKey(ctx context.Context) (*model.SigningKey, error){
+	res_key_list, err := s.getSigningKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, res_key := range *res_key_list {
+		if res_key.ActiveAt.After(now) {
+			continue
+		}
+		if res_key.RetiredAt == nil || res_key.RetiredAt.After(now) {
+			return &res_key, nil
+		}
+	}
+
+	return nil, erro.ErrNotFound
+}
+
+// About create a new signing key and retire the active one
+// The new key is published at once but only signs after the activation delay (the caches of the replicas
+// and the JWKS of the receivers), the retired key signs until then and stays published during the KeyPublishTime
+func (s *WorkerService) RotateSigningKey(ctx context.Context) (*model.SigningKey, error){
+	childLogger.Info().Str("func","RotateSigningKey").Send()
+
+	return s.createSigningKey(ctx, true)
+}
+
+// About create the first signing key when there is no active one
+// Every replica runs it at startup, the lock lets only the first one create the key
+func (s *WorkerService) EnsureSigningKey(ctx context.Context) error{
+	childLogger.Info().Str("func","EnsureSigningKey").Send()
+
+	_, err := s.createSigningKey(ctx, false)
+	return err
+}
+
+// About the delay before a rotated key signs, the time the replicas cache the keys plus the time the receivers cache the JWKS
+func (s *WorkerService) activationDelay() int {
+	return s.signingConfig.KeyCacheTime + s.signingConfig.JWKSMaxAge
+}
+
+// About the time the receivers may cache the JWKS
+func (s *WorkerService) JWKSMaxAge() int {
+	return s.signingConfig.JWKSMaxAge
+}
+
+// About create a signing key under the signing key lock (serialized across the replicas)
+// Without rotate nothing is created when there is already an active key, the first key is active at once
+func (s *WorkerService) createSigningKey(ctx context.Context, rotate bool) (res_key *model.SigningKey, err error){
+	childLogger.Debug().Str("func","createSigningKey").Bool("rotate", rotate).Send()
+
+	//Trace
+	span := tracerProvider.Span(ctx, "service.createSigningKey")
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	if len(s.signingConfig.EncryptionKey) == 0 {
+		span.End()
+		return nil, erro.ErrKeyEncryption
+	}
+
+	// Get the database connection
+	tx, conn, err := s.workerRepository.DatabasePGServer.StartTx(ctx)
+	if err != nil {
+		span.End()
+		return nil, err
+	}
+	defer s.workerRepository.DatabasePGServer.ReleaseTx(conn)
+
+	// Handle the transaction
+	defer func() {
+		if err != nil {
+			childLogger.Info().Interface("trace-request-id", trace_id ).Msg("ROLLBACK TX !!!")
+			tx.Rollback(ctx)
+		} else {
+			childLogger.Info().Interface("trace-request-id", trace_id ).Msg("COMMIT TX !!!")
+			// a key not committed is not returned, the caller gets the error
+			if err = tx.Commit(ctx); err != nil {
+				childLogger.Error().Err(err).Interface("trace-request-id", trace_id ).Msg("error commit signing key")
+				res_key = nil
+			} else {
+				// force reload only after the commit, a reload before it would cache the old key again
+				s.keyMutex.Lock()
+				s.signingKeys = nil
+				s.keyMutex.Unlock()
+			}
+		}	
+		span.End()
+	}()
+
+	err = s.workerRepository.LockSigningKeys(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !rotate {
+		var active bool
+		active, err = s.workerRepository.HasActiveSigningKey(ctx, tx)
+		if err != nil || active {
+			return nil, err
+		}
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	signingKey := model.SigningKey{
+		KeyID: uuid.New().String(),
+		Algorithm: "ED25519",
+		PublicKey: publicKey,
+		Encrypted: true,
+		CreatedAt: time.Now(),
+	}
+	signingKey.PrivateKey, err = s.sealPrivateKey(signingKey.KeyID, privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	activeDelay := 0
+	if rotate {
+		activeDelay = s.activationDelay()
+	}
+
+	_, err = s.workerRepository.RetireSigningKeys(ctx, tx, activeDelay)
+	if err != nil {
+		return nil, err
+	}
+
+	res_key, err = s.workerRepository.InsertSigningKey(ctx, tx, signingKey, activeDelay)
+	if err != nil {
+		return nil, err
+	}
+
+	childLogger.Info().Str("kid", res_key.KeyID).Time("active_at", res_key.ActiveAt).Msg("SIGNING KEY CREATED !!!")
+
+	return res_key, nil
+}
+
+// About encrypt a private key (aes-256-gcm, the kid is authenticated with it), the nonce is stored in front
+func (s *WorkerService) sealPrivateKey(keyID string, privateKey []byte) ([]byte, error){
+	aead, err := s.keyCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, privateKey, []byte(keyID)), nil
+}
+
+// About decrypt a private key
+func (s *WorkerService) openPrivateKey(signingKey model.SigningKey) ([]byte, error){
+	aead, err := s.keyCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(signingKey.PrivateKey) < aead.NonceSize() {
+		return nil, erro.ErrKeyEncryption
+	}
+	nonce, sealed := signingKey.PrivateKey[:aead.NonceSize()], signingKey.PrivateKey[aead.NonceSize():]
+
+	privateKey, err := aead.Open(nil, nonce, sealed, []byte(signingKey.KeyID))
+	if err != nil {
+		return nil, errors.Join(erro.ErrKeyEncryption, err)
+	}
+	return privateKey, nil
+}
+
+// About the cipher of the private keys
+func (s *WorkerService) keyCipher() (cipher.AEAD, error){
+	if len(s.signingConfig.EncryptionKey) == 0 {
+		return nil, erro.ErrKeyEncryption
+	}
+
+	block, err := aes.NewCipher(s.signingConfig.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// About get the published public keys as JWKS
+func (s *WorkerService) GetJWKS(ctx context.Context) (*pkg_webhook.JWKS, error){
+	childLogger.Debug().Str("func","GetJWKS").Send()
+
+	res_key_list, err := s.getSigningKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	jwks := pkg_webhook.JWKS{Keys: []pkg_webhook.JWK{}}
+	for _, res_key := range *res_key_list {
+		jwks.Keys = append(jwks.Keys, pkg_webhook.NewJWK(res_key.KeyID, ed25519.PublicKey(res_key.PublicKey)))
+	}
+
+	return &jwks, nil
+}
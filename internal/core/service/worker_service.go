@@ -4,67 +4,85 @@ import(
 	"fmt"
 	"time"
 	"context"
-	"net/http"
-	"encoding/json"
+	"strconv"
+	"sync"
+	"crypto/ed25519"
 	"errors"
 
 	"github.com/rs/zerolog/log"
 
 	"github.com/go-worker-webhook/internal/adapter/database"
+	"github.com/go-worker-webhook/internal/adapter/client"
 	"github.com/go-worker-webhook/internal/core/model"
+	"github.com/go-worker-webhook/internal/core/handler"
 	"github.com/go-worker-webhook/internal/core/erro"
+	pkg_webhook "github.com/go-worker-webhook/pkg/webhook"
 	go_core_observ "github.com/eliezerraj/go-core/observability"
-	go_core_api "github.com/eliezerraj/go-core/api"
 )
 
 var childLogger = log.With().Str("component","go-worker-webhook").Str("package","internal.core.service").Logger()
 var tracerProvider go_core_observ.TracerProvider
-var apiService go_core_api.ApiService
+
+// max time to record the result of a delivery, also when the dispatcher is shutting down
+const updateTimeout = 5 * time.Second
 
 type WorkerService struct {
-	goCoreRestApiService	go_core_api.ApiService
+	webHookClient	*client.WebHookClient
 	workerRepository *database.WorkerRepository
+	retryPolicy		*model.RetryPolicy
+	signingConfig	*model.SigningConfig
+	keyMutex		sync.Mutex
+	signingKeys		*[]model.SigningKey
+	signingKeysAt	time.Time
+	breakerConfig	*model.BreakerConfig
+	breakerMutex	sync.Mutex
+	breakers		map[string]*model.CircuitBreaker
+	eventRegistry	*handler.Registry
 }
 
 // About create a new worker service
-func NewWorkerService(	goCoreRestApiService	go_core_api.ApiService,	
-						workerRepository *database.WorkerRepository ) *WorkerService{
+func NewWorkerService(	webHookClient	*client.WebHookClient,	
+						workerRepository *database.WorkerRepository,
+						retryPolicy *model.RetryPolicy,
+						signingConfig *model.SigningConfig,
+						breakerConfig *model.BreakerConfig,
+						eventRegistry *handler.Registry ) *WorkerService{
 	childLogger.Debug().Str("func","NewWorkerService").Send()
 
-	return &WorkerService{
-		goCoreRestApiService: goCoreRestApiService,
+	workerService := &WorkerService{
+		webHookClient: webHookClient,
 		workerRepository: workerRepository,
+		retryPolicy: retryPolicy,
+		signingConfig: signingConfig,
+		breakerConfig: breakerConfig,
+		breakers: make(map[string]*model.CircuitBreaker),
+		eventRegistry: eventRegistry,
 	}
-}
+	workerService.registerBreakerMetrics()
 
-// About handle/convert http status code
-func errorStatusCode(statusCode int, serviceName string, msg_err error) error{
-	childLogger.Info().Str("func","errorStatusCode").Interface("serviceName", serviceName).Interface("statusCode", statusCode).Send()
-	var err error
-	switch statusCode {
-		case http.StatusUnauthorized:
-			err = erro.ErrUnauthorized
-		case http.StatusForbidden:
-			err = erro.ErrHTTPForbiden
-		case http.StatusNotFound:
-			err = erro.ErrNotFound
-		default:
-			err = errors.New(fmt.Sprintf("service %s in outage => cause error: %s", serviceName, msg_err.Error() ))
-		}
-	return err
+	return workerService
 }
 
 // About insert webhook
-func (s *WorkerService) InsertWebHook(ctx context.Context, webhook *model.WebHook) (*model.WebHook, error){
+func (s *WorkerService) InsertWebHook(ctx context.Context, webhook *model.WebHook) (*[]model.WebHook, error){
 	childLogger.Info().Str("func","InsertWebHook").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
 
+	return s.InsertWebHooks(ctx, []model.WebHook{*webhook})
+}
+
+// About insert the webhooks of a batch of events in one transaction
+// The subscriptions of all the receivers are resolved in one query and the rows inserted in one batch
+func (s *WorkerService) InsertWebHooks(ctx context.Context, webhooks []model.WebHook) (*[]model.WebHook, error){
+	childLogger.Info().Str("func","InsertWebHooks").Int("events", len(webhooks)).Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+
 	//Trace
-	span := tracerProvider.Span(ctx, "service.InsertWebHook")
+	span := tracerProvider.Span(ctx, "service.InsertWebHooks")
 	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
 
 	// Get the database connection
 	tx, conn, err := s.workerRepository.DatabasePGServer.StartTx(ctx)
 	if err != nil {
+		span.End()
 		return nil, err
 	}
 	defer s.workerRepository.DatabasePGServer.ReleaseTx(conn)
@@ -82,59 +100,318 @@ func (s *WorkerService) InsertWebHook(ctx context.Context, webhook *model.WebHoo
 	}()
 
 	// ------------------------  STEP-1 ----------------------------------//
-	childLogger.Info().Str("func","InsertWebHook").Msg("===> STEP - 01 (GET WEBHOOK CONFIG) <===")
-	
-	switch webhook.Type {
-	case "TOPIC:PIX":
-		pixTransaction := model.PixTransaction{}
-		json.Unmarshal([]byte(webhook.Payload), &pixTransaction)
-
-		childLogger.Info().Interface("payload:", pixTransaction).Send()
+	childLogger.Info().Str("func","InsertWebHooks").Msg("===> STEP - 01 (GET WEBHOOK CONFIG) <===")
+
+	// the receivers of all the events
+	list_receiver := []model.WebHook{}
+	for i := range webhooks {
+		eventHandler, ok := s.eventRegistry.Get(webhooks[i].Type)
+		if !ok {
+			childLogger.Info().Interface("topic:", webhooks[i].Type).Msg("NOT REGISTER, MSG DISCARDED  !!!")
+			continue
+		}
 
-		webhook.Receiver = "ACCOUNT:" + pixTransaction.AccountFrom.AccountID
-	default:
-		childLogger.Info().Interface("topic:", webhook.Type).Msg("NOT REGISTER, MSG DISCARDED  !!!")
-		return nil, nil
+		var res_receiver_list []model.WebHook
+		res_receiver_list, err = s.resolveReceivers(eventHandler, &webhooks[i])
+		if err != nil {
+			return nil, err
+		}
+		list_receiver = append(list_receiver, res_receiver_list...)
+	}
+	if len(list_receiver) == 0 {
+		return &[]model.WebHook{}, nil
 	}
 
-	res, err := s.workerRepository.GetSetupWebHook(ctx, *webhook)
+	// the subscriptions of all the receivers
+	var res_setup_list *[]model.WebHook
+	res_setup_list, err = s.workerRepository.GetSetupWebHooks(ctx, list_receiver)
 	if err != nil {
-		childLogger.Info().Err(err).Send()
-		webhook.Status = "IN-QUEUE:MSG-DISCARDED-NO-WEBHOOK-SETUP"
-	} else {
-		webhook.Status = "IN-QUEUE:WAITING-FOR-SEND"
-		webhook.ID = res.ID
-		webhook.Receiver = res.Receiver
-		webhook.Host = res.Host
-		webhook.Url = res.Url
-		webhook.Method = res.Method
+		return nil, err
+	}
+	setups := make(map[string][]model.WebHook)
+	for _, res_setup := range *res_setup_list {
+		key := res_setup.Receiver + "|" + res_setup.Type
+		setups[key] = append(setups[key], res_setup)
+	}
+
+	// fan-out, every subscription of each receiver gets its own webhook
+	list_webhook := []model.WebHook{}
+
+	for _, receiver := range list_receiver {
+		res_setups, ok := setups[receiver.Receiver + "|" + receiver.Type]
+		if !ok {
+			configErr := erro.NewDeliveryError(erro.ErrConfigMissing, 0, erro.ErrNotFound)
+			childLogger.Info().Err(configErr).Str("receiver", receiver.Receiver).Send()
+			receiver.Status = "IN-QUEUE:MSG-DISCARDED-NO-WEBHOOK-SETUP"
+			receiver.ErrorClass = configErr.Class()
+			list_webhook = append(list_webhook, receiver)
+			continue
+		}
+
+		for _, res_setup := range res_setups {
+			res_webhook := receiver
+			res_webhook.Status = "IN-QUEUE:WAITING-FOR-SEND"
+			res_webhook.ConfigID = res_setup.ID
+			nextAttemptAt := time.Now()
+			res_webhook.NextAttemptAt = &nextAttemptAt
+			res_webhook.Receiver = res_setup.Receiver
+			res_webhook.Host = res_setup.Host
+			res_webhook.Url = res_setup.Url
+			res_webhook.Method = res_setup.Method
+			res_webhook.OrderingKey = orderingKeyOf(&res_setup, receiver.EventKey)
+			list_webhook = append(list_webhook, res_webhook)
+		}
 	}
 
 	// ------------------------  STEP-2 ----------------------------------//
-	childLogger.Info().Str("func","InsertWebHook").Msg("===> STEP - 02 (INSERT WEBHOOK) <===")
+	childLogger.Info().Str("func","InsertWebHooks").Msg("===> STEP - 02 (INSERT WEBHOOK) <===")
 
-	//webhook.Status = "IN-QUEUE:WAITING-FOR-SEND"
-	res, err = s.workerRepository.InsertWebHook(ctx, tx, *webhook)
+	// a message consumed again (redelivery) is a no-op, the webhooks already exist (id 0)
+	var res_id_list []int
+	res_id_list, err = s.workerRepository.InsertWebHooks(ctx, tx, list_webhook)
 	if err != nil {
 		return nil, err
 	}
-	webhook.ID = res.ID	
 
-	return webhook, nil
+	list_inserted := []model.WebHook{}
+	for i, res_webhook := range list_webhook {
+		if res_id_list[i] == 0 {
+			childLogger.Info().Str("event_id", res_webhook.EventID).Str("receiver", res_webhook.Receiver).Msg("WEBHOOK ALREADY EXISTS, SKIPPED !!!")
+			continue
+		}
+		res_webhook.ID = res_id_list[i]
+
+		// the ordered webhooks get the next sequence of its ordering key (only when inserted, no gap)
+		if res_webhook.OrderingKey != "" {
+			res_webhook.SequenceNo, err = s.workerRepository.NextSequence(ctx, tx, res_webhook.OrderingKey)
+			if err != nil {
+				return nil, err
+			}
+			_, err = s.workerRepository.SetSequenceWebHook(ctx, tx, res_webhook)
+			if err != nil {
+				return nil, err
+			}
+		}
+		list_inserted = append(list_inserted, res_webhook)
+	}
+
+	return &list_inserted, nil
+}
+
+// About decode and validate the event with its handler, one webhook per receiver with its own payload
+func (s *WorkerService) resolveReceivers(eventHandler handler.EventHandler, webhook *model.WebHook) ([]model.WebHook, error){
+	event, err := eventHandler.Decode(webhook.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	childLogger.Info().Interface("payload:", event).Send()
+
+	err = eventHandler.Validate(event)
+	if err != nil {
+		return nil, err
+	}
+
+	receivers, err := eventHandler.Receivers(event)
+	if err != nil {
+		return nil, err
+	}
+
+	list_receiver := []model.WebHook{}
+	for _, receiver := range receivers {
+		body, err := eventHandler.Body(event, receiver)
+		if err != nil {
+			return nil, err
+		}
+
+		res_receiver := *webhook
+		res_receiver.Receiver = receiver.Receiver
+		res_receiver.Payload = body
+		res_receiver.EventKey = eventHandler.Key(event)
+		res_receiver.EventID = eventHandler.EventID(event)
+		// without a domain id, the kafka coordinates identify the event
+		if res_receiver.EventID == "" && res_receiver.Topic != "" {
+			res_receiver.EventID = fmt.Sprintf("KAFKA:%v:%v:%v", res_receiver.Topic, res_receiver.KafkaPartition, res_receiver.KafkaOffset)
+		}
+		list_receiver = append(list_receiver, res_receiver)
+	}
+
+	return list_receiver, nil
 }
 
 // About send the webhook
+// The webhook was already claimed, so no database connection is held during the http call,
+// the status is recorded afterwards in a short transaction
 func (s *WorkerService) SendWebHook(ctx context.Context, webhook *model.WebHook) (*model.WebHook, error){
 	childLogger.Info().Str("func","SendWebHook").Send()
 
 	//Trace
 	span := tracerProvider.Span(ctx, "service.SendWebHook")
-	trace_id := fmt.Sprintf("%v", "11111-222222")
+	defer span.End()
+
+	// ------------------------  STEP-1 ----------------------------------//
+	childLogger.Info().Str("func","SendWebHook").Msg("===> STEP - 01 (SEND WEBHOOK) <===")
+
+	webHookResponse, headers, err := s.callWebHook(ctx, webhook)
+	statusCode := webHookResponse.StatusCode
+	deliveryErr := classifyResponse(webHookResponse, err, webhook.ResponseRules)
+	// a failure before the http call (signing) says nothing about the host
+	var deliveryError *erro.DeliveryError
+	if !errors.As(err, &deliveryError) {
+		s.recordDelivery(webhook.Host, deliveryErr)
+	}
+
+	// setting status and scheduling the next attempt
+	webhook.Attempts = webhook.Attempts + 1
+	webhook.ErrorClass = erro.ClassOf(deliveryErr)
+	switch {
+	case deliveryErr == nil:
+		webhook.Status = fmt.Sprintf("IN-QUEUE:SENDED:%v", statusCode)
+		webhook.NextAttemptAt = nil
+		webhook.LastError = ""
+	case erro.IsRetryable(deliveryErr):
+		webhook.Status = fmt.Sprintf("IN-QUEUE:ERROR:%v", statusCode)
+		webhook.LastError = deliveryErr.Error()
+		webhook.NextAttemptAt = s.nextAttemptAt(webhook, deliveryErr)
+		// attempts exhausted, terminal state until an operator replay it
+		if webhook.NextAttemptAt == nil {
+			webhook.Status = "IN-QUEUE:DEAD-LETTER"
+		}
+	default:
+		// permanent and endpoint gone are not retried
+		webhook.Status = "IN-QUEUE:DEAD-LETTER"
+		webhook.NextAttemptAt = nil
+		webhook.LastError = deliveryErr.Error()
+	}
+
+	deliveryAttempt := model.DeliveryAttempt{
+		WebHookID: webhook.ID,
+		Attempt: webhook.Attempts,
+		RequestHeaders: headers,
+		ResponseStatus: statusCode,
+		ResponseBody: webHookResponse.Body,
+		Latency: webHookResponse.Latency.Milliseconds(),
+		ErrorClass: webhook.ErrorClass,
+		Error: webhook.LastError,
+		PodName: webhook.ClaimedBy,
+		CreatedAt: time.Now(),
+	}
+	
+	// ------------------------  STEP-2 ----------------------------------//
+	childLogger.Info().Str("func","SendWebHook").Msg("===> STEP - 02 (UPDATE) <===")
+
+	// the result is recorded even when the call was cancelled (shutdown), otherwise it is sent again after the lease
+	updateCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), updateTimeout)
+	defer cancel()
+
+	err = s.updateWebHook(updateCtx, webhook, &deliveryAttempt, errors.Is(deliveryErr, erro.ErrEndpointGone))
+	if err != nil {
+		return nil, err
+	}
+
+	// the delivery error is returned to the dispatcher (the webhook is already recorded)
+	return webhook, deliveryErr
+}
+
+// About call the receiver endpoint, signing the payload
+func (s *WorkerService) callWebHook(ctx context.Context, webhook *model.WebHook) (*client.WebHookResponse, map[string]string, error){
+	childLogger.Info().Str("func","callWebHook").Send()
+
+	//Trace
+	span := tracerProvider.Span(ctx, "service.callWebHook")
+	defer span.End()
+
+	// an invalid webhook_config is not sent (not retried), a delivered webhook would be sent again
+	responseRules, err := validResponseRules(webhook.ResponseRules)
+	if err != nil {
+		return &client.WebHookResponse{}, nil, erro.NewDeliveryError(erro.ErrConfigMissing, 0, err)
+	}
+	webhook.ResponseRules = responseRules
+
+	signer, err := s.signerOf(ctx, webhook)
+	if err != nil {
+		// a signing setup missing is not retried, a failure loading the signing key is
+		var deliveryError *erro.DeliveryError
+		if errors.As(err, &deliveryError) {
+			return &client.WebHookResponse{}, nil, deliveryError
+		}
+		return &client.WebHookResponse{}, nil, erro.NewDeliveryError(erro.ErrRetryable, 0, err)
+	}
+
+	// prepare headers (the webhook-id is the same in every attempt)
+	headers := signer.Headers(strconv.Itoa(webhook.ID), time.Now(), webhook.Payload)
+	headers["Content-Type"] = "application/json;charset=UTF-8"
+	if webhook.SequenceNo > 0 {
+		headers[pkg_webhook.HeaderSequence] = strconv.FormatInt(webhook.SequenceNo, 10)
+	}
+
+	webHookResponse, err := s.webHookClient.Send(ctx,
+												webhook.Method,
+												webhook.Host + webhook.Url,
+												headers,
+												webhook.Payload)
+	return webHookResponse, headers, err
+}
+
+// About the ordering key of the webhook_config ordering mode (empty when not ordered)
+// The key is scoped by the webhook_config, each subscription keeps its own sequence
+func orderingKeyOf(webhookConfig *model.WebHook, eventKey string) string {
+	switch webhookConfig.OrderingMode {
+	case "RECEIVER":
+		return fmt.Sprintf("%v:%v", webhookConfig.ID, webhookConfig.Receiver)
+	case "TRANSACTION":
+		if eventKey == "" {
+			return fmt.Sprintf("%v:%v", webhookConfig.ID, webhookConfig.Receiver)
+		}
+		return fmt.Sprintf("%v:TRANSACTION:%v", webhookConfig.ID, eventKey)
+	default:
+		return ""
+	}
+}
+
+// About create the signer of the webhook_config signature mode
+func (s *WorkerService) signerOf(ctx context.Context, webhook *model.WebHook) (*pkg_webhook.Signer, error){
+	switch webhook.SignatureMode {
+	case "ED25519":
+		// sign with the active key of the service, the receiver verifies with the JWKS
+		signingKey, err := s.activeSigningKey(ctx)
+		if err != nil {
+			return nil, err
+		}
+		signer, _ := pkg_webhook.NewSigner()
+		signer.AddEd25519(signingKey.KeyID, ed25519.PrivateKey(signingKey.PrivateKey))
+		return signer, nil
+	case "NONE":
+		return pkg_webhook.NewSigner()
+	default:
+		// never send unsigned by mistake, unsigned is the explicit NONE mode
+		if webhook.SigningSecret == "" {
+			return nil, erro.NewDeliveryError(erro.ErrConfigMissing, 0, fmt.Errorf("signing_secret missing in webhook_config %v", webhook.ConfigID))
+		}
+		// sign with the current secret and, during the rotation overlap, with the previous one
+		secrets := []string{webhook.SigningSecret}
+		if webhook.SigningSecretPrevious != "" && 
+			webhook.SigningSecretPreviousExpiresAt != nil && 
+			time.Now().Before(*webhook.SigningSecretPreviousExpiresAt) {
+			secrets = append(secrets, webhook.SigningSecretPrevious)
+		}
+		return pkg_webhook.NewSigner(secrets...)
+	}
+}
+
+// About record the webhook status and the attempt (and disable the webhook_config when the endpoint is gone)
+func (s *WorkerService) updateWebHook(ctx context.Context, webhook *model.WebHook, deliveryAttempt *model.DeliveryAttempt, disable bool) error{
+	childLogger.Info().Str("func","updateWebHook").Send()
+
+	//Trace
+	span := tracerProvider.Span(ctx, "service.updateWebHook")
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
 
 	// Get the database connection
 	tx, conn, err := s.workerRepository.DatabasePGServer.StartTx(ctx)
 	if err != nil {
-		return nil, err
+		span.End()
+		return err
 	}
 	defer s.workerRepository.DatabasePGServer.ReleaseTx(conn)
 
@@ -150,63 +427,154 @@ func (s *WorkerService) SendWebHook(ctx context.Context, webhook *model.WebHook)
 		span.End()
 	}()
 
-	// ------------------------  STEP-1 ----------------------------------//
-	childLogger.Info().Str("func","SendWebHook").Msg("===> STEP - 02 (SEND WEBHOOK) <===")
+	update := time.Now()
+	webhook.UpdatedAt = &update
 
-	// prepare headers
-	headers := map[string]string{
-		"Content-Type":"application/json;charset=UTF-8",
+	// update status webhook
+	res_update, err := s.workerRepository.UpdateWebHook(ctx, tx, *webhook)
+	if err != nil {
+		return err
 	}
-
-	httpClient := go_core_api.HttpClient {
-		Url:	webhook.Host + webhook.Url,
-		Method: webhook.Method,
-		Timeout: 10,
-		Headers: &headers,
+	if res_update == 0 {
+		err = erro.ErrUpdate
+		return err
 	}
 
-	_, statusCode, err := apiService.CallRestApiV1(	ctx,
-													s.goCoreRestApiService.Client,
-													httpClient, 
-													webhook.Payload)
+	_, err = s.workerRepository.InsertDeliveryAttempt(ctx, tx, *deliveryAttempt)
 	if err != nil {
-		childLogger.Error().Err(err).Interface("error",errorStatusCode(statusCode, webhook.Host, err)).Send()
+		return err
 	}
 
-	// setting status
-	if statusCode == 200 {
-		webhook.Status = fmt.Sprintf("IN-QUEUE:SENDED:%v", statusCode)
-	} else {
-		webhook.Status = fmt.Sprintf("IN-QUEUE:ERROR:%v", statusCode)
+	if disable {
+		_, err = s.workerRepository.DisableWebHookConfig(ctx, tx, webhook.ConfigID, webhook.LastError)
+		if err != nil {
+			return err
+		}
+		childLogger.Warn().Int("webhook_config", webhook.ConfigID).Str("reason", webhook.LastError).Msg("WEBHOOK CONFIG DISABLED !!!")
 	}
-	
-	// ------------------------  STEP-2 ----------------------------------//
-	childLogger.Info().Str("func","SendWebHook").Msg("===> STEP - 03 (UPDATE) <===")
 
-	update := time.Now()
-	webhook.UpdatedAt = &update
+	return nil
+}
 
-	// update status payment
-	res_update, err := s.workerRepository.UpdateWebHook(ctx, tx, *webhook)
+// About claim a batch of webhook to send 
+func (s *WorkerService) ClaimWebHooks(ctx context.Context, webhook *model.WebHook, leaseTime int, limit int) (*[]model.WebHook, error){
+	childLogger.Debug().Str("func","ClaimWebHooks").Send()
+	
+	res_webhook_list, err := s.workerRepository.ClaimWebHooks(ctx, webhook, leaseTime, limit)
 	if err != nil {
 		return nil, err
 	}
-	if res_update == 0 {
-		err = erro.ErrUpdate
-		return nil, err
+
+	return res_webhook_list, nil
+}
+
+// About give back a claimed webhook not sent
+func (s *WorkerService) ReleaseWebHook(ctx context.Context, webhook *model.WebHook) error{
+	childLogger.Debug().Str("func","ReleaseWebHook").Send()
+	
+	_, err := s.workerRepository.ReleaseWebHook(ctx, *webhook)
+	if err != nil {
+		return err
 	}
 
-	return webhook, nil
+	return nil
 }
 
-// About get all webhook to send 
-func (s *WorkerService) GetWebHook(ctx context.Context, webhook *model.WebHook) (*model.WebHook, error){
-	childLogger.Debug().Str("func","GetWebHook").Send()
+// About renew the lease of a claimed webhook, false when the lease was lost (expired or claimed by another replica)
+func (s *WorkerService) RenewWebHook(ctx context.Context, webhook *model.WebHook, leaseTime int) (bool, error){
+	childLogger.Debug().Str("func","RenewWebHook").Int("webhook", webhook.ID).Send()
 	
-	webhook, err := s.workerRepository.GetWebHook(ctx, webhook)
+	res, err := s.workerRepository.RenewWebHook(ctx, *webhook, leaseTime)
+	if err != nil {
+		return false, err
+	}
+
+	return res == 1, nil
+}
+
+// About postpone a claimed webhook, without spending an attempt
+func (s *WorkerService) DeferWebHook(ctx context.Context, webhook *model.WebHook, until time.Time) error{
+	childLogger.Debug().Str("func","DeferWebHook").Int("webhook", webhook.ID).Time("until", until).Send()
+	
+	webhook.NextAttemptAt = &until
+	_, err := s.workerRepository.DeferWebHook(ctx, *webhook)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// About count the webhooks pending delivery (backpressure)
+func (s *WorkerService) CountPendingWebHooks(ctx context.Context) (int64, error){
+	childLogger.Debug().Str("func","CountPendingWebHooks").Send()
+
+	return s.workerRepository.CountPendingWebHooks(ctx)
+}
+
+// About replay the dead-letter webhooks (single row, by receiver, by error class and/or by time range)
+func (s *WorkerService) ReplayWebHooks(ctx context.Context, replayFilter *model.ReplayFilter) (int64, error){
+	childLogger.Info().Str("func","ReplayWebHooks").Interface("replayFilter", replayFilter).Send()
+
+	//Trace
+	span := tracerProvider.Span(ctx, "service.ReplayWebHooks")
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	// at least one filter and who is replaying are mandatory
+	if replayFilter.ReplayedBy == "" {
+		span.End()
+		return 0, erro.ErrInvalid
+	}
+	if replayFilter.ID == 0 && replayFilter.Receiver == "" && replayFilter.ErrorClass == "" && replayFilter.From == nil && replayFilter.To == nil {
+		span.End()
+		return 0, erro.ErrInvalid
+	}
+
+	// Get the database connection
+	tx, conn, err := s.workerRepository.DatabasePGServer.StartTx(ctx)
+	if err != nil {
+		span.End()
+		return 0, err
+	}
+	defer s.workerRepository.DatabasePGServer.ReleaseTx(conn)
+
+	// Handle the transaction
+	defer func() {
+		if err != nil {
+			childLogger.Info().Interface("trace-request-id", trace_id ).Msg("ROLLBACK TX !!!")
+			tx.Rollback(ctx)
+		} else {
+			childLogger.Info().Interface("trace-request-id", trace_id ).Msg("COMMIT TX !!!")
+			tx.Commit(ctx)
+		}	
+		span.End()
+	}()
+
+	res_replay, err := s.workerRepository.ReplayWebHooks(ctx, tx, *replayFilter)
+	if err != nil {
+		return 0, err
+	}
+
+	childLogger.Info().Int64("replayed", res_replay).Str("replayed_by", replayFilter.ReplayedBy).Msg("WEBHOOK REPLAYED !!!")
+
+	return res_replay, nil
+}
+
+// About list the delivery attempts of a webhook
+func (s *WorkerService) ListDeliveryAttempts(ctx context.Context, webhookID int) (*[]model.DeliveryAttempt, error){
+	childLogger.Info().Str("func","ListDeliveryAttempts").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+
+	//Trace
+	span := tracerProvider.Span(ctx, "service.ListDeliveryAttempts")
+	defer span.End()
+
+	res_attempt_list, err := s.workerRepository.ListDeliveryAttempts(ctx, webhookID)
 	if err != nil {
 		return nil, err
 	}
+	if len(*res_attempt_list) == 0 {
+		return nil, erro.ErrNotFound
+	}
 
-	return webhook, nil
+	return res_attempt_list, nil
 }
\ No newline at end of file
@@ -0,0 +1,48 @@
+package configuration
+
+import(
+	"os"
+	"strconv"
+
+	"github.com/joho/godotenv"
+	"github.com/go-worker-webhook/internal/core/model"
+)
+
+func GetBackpressureEnv() model.BackpressureConfig {
+	childLogger.Info().Str("func","GetBackpressureEnv").Send()
+
+	err := godotenv.Load(".env")
+	if err != nil {
+		childLogger.Info().Err(err).Send()
+	}
+
+	// defaults
+	backpressureConfig := model.BackpressureConfig{
+		HighWater: 100000,
+		LowWater: 50000,
+		CheckInterval: 5000,
+	}
+
+	if os.Getenv("BACKPRESSURE_HIGH_WATER") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("BACKPRESSURE_HIGH_WATER"))
+		backpressureConfig.HighWater = intVar
+	}
+	if os.Getenv("BACKPRESSURE_LOW_WATER") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("BACKPRESSURE_LOW_WATER"))
+		backpressureConfig.LowWater = intVar
+	}
+	if os.Getenv("BACKPRESSURE_CHECK_MS") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("BACKPRESSURE_CHECK_MS"))
+		backpressureConfig.CheckInterval = intVar
+	}
+
+	// the low-water mark must be under the high-water mark
+	if backpressureConfig.LowWater >= backpressureConfig.HighWater {
+		backpressureConfig.LowWater = backpressureConfig.HighWater / 2
+	}
+	if backpressureConfig.CheckInterval < 1 {
+		backpressureConfig.CheckInterval = 5000
+	}
+
+	return backpressureConfig
+}
@@ -0,0 +1,35 @@
+package configuration
+
+import(
+	"os"
+	"strconv"
+
+	"github.com/joho/godotenv"
+	"github.com/go-worker-webhook/internal/core/model"
+)
+
+func GetBreakerEnv() model.BreakerConfig {
+	childLogger.Info().Str("func","GetBreakerEnv").Send()
+
+	err := godotenv.Load(".env")
+	if err != nil {
+		childLogger.Info().Err(err).Send()
+	}
+
+	// defaults
+	breakerConfig := model.BreakerConfig{
+		FailureThreshold: 5,
+		OpenTime: 30000,
+	}
+
+	if os.Getenv("BREAKER_FAILURE_THRESHOLD") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("BREAKER_FAILURE_THRESHOLD"))
+		breakerConfig.FailureThreshold = intVar
+	}
+	if os.Getenv("BREAKER_OPEN_MS") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("BREAKER_OPEN_MS"))
+		breakerConfig.OpenTime = intVar
+	}
+
+	return breakerConfig
+}
@@ -0,0 +1,52 @@
+package configuration
+
+import(
+	"os"
+	"strconv"
+
+	"github.com/joho/godotenv"
+	"github.com/go-worker-webhook/internal/core/model"
+)
+
+func GetConsumerEnv() model.ConsumerConfig {
+	childLogger.Info().Str("func","GetConsumerEnv").Send()
+
+	err := godotenv.Load(".env")
+	if err != nil {
+		childLogger.Info().Err(err).Send()
+	}
+
+	// defaults
+	consumerConfig := model.ConsumerConfig{
+		MaxRetry: 3,
+		RetryDelay: 1000,
+		BatchSize: 1,
+		BatchTimeout: 500,
+	}
+
+	if os.Getenv("KAFKA_TOPIC_DLQ") !=  "" {
+		consumerConfig.DlqTopic = os.Getenv("KAFKA_TOPIC_DLQ")
+	}
+	// without dead-letter topic the messages that would go to it are skipped (logged and committed), only when explicit
+	if os.Getenv("KAFKA_DLQ_SKIP") ==  "true" {
+		consumerConfig.DlqSkip = true
+	}
+	if os.Getenv("KAFKA_MAX_RETRY") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("KAFKA_MAX_RETRY"))
+		consumerConfig.MaxRetry = intVar
+	}
+	if os.Getenv("KAFKA_RETRY_DELAY_MS") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("KAFKA_RETRY_DELAY_MS"))
+		consumerConfig.RetryDelay = intVar
+	}
+	if os.Getenv("KAFKA_BATCH_SIZE") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("KAFKA_BATCH_SIZE"))
+		consumerConfig.BatchSize = intVar
+	}
+	if os.Getenv("KAFKA_BATCH_TIMEOUT_MS") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("KAFKA_BATCH_TIMEOUT_MS"))
+		consumerConfig.BatchTimeout = intVar
+	}
+
+	return consumerConfig
+}
@@ -0,0 +1,55 @@
+package configuration
+
+import(
+	"os"
+	"strconv"
+
+	"github.com/joho/godotenv"
+	"github.com/go-worker-webhook/internal/core/model"
+)
+
+func GetDispatcherEnv() model.DispatcherConfig {
+	childLogger.Info().Str("func","GetDispatcherEnv").Send()
+
+	err := godotenv.Load(".env")
+	if err != nil {
+		childLogger.Info().Err(err).Send()
+	}
+
+	// defaults
+	dispatcherConfig := model.DispatcherConfig{
+		WorkerCount: 5,
+		QueueDepth: 100,
+		BatchSize: 50,
+		PollInterval: 1000,
+		LeaseTime: 60,
+		ThrottleTime: 30000,
+	}
+
+	if os.Getenv("DISPATCHER_WORKERS") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("DISPATCHER_WORKERS"))
+		dispatcherConfig.WorkerCount = intVar
+	}
+	if os.Getenv("DISPATCHER_QUEUE_DEPTH") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("DISPATCHER_QUEUE_DEPTH"))
+		dispatcherConfig.QueueDepth = intVar
+	}
+	if os.Getenv("DISPATCHER_BATCH_SIZE") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("DISPATCHER_BATCH_SIZE"))
+		dispatcherConfig.BatchSize = intVar
+	}
+	if os.Getenv("DISPATCHER_POLL_INTERVAL_MS") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("DISPATCHER_POLL_INTERVAL_MS"))
+		dispatcherConfig.PollInterval = intVar
+	}
+	if os.Getenv("DISPATCHER_LEASE_SEC") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("DISPATCHER_LEASE_SEC"))
+		dispatcherConfig.LeaseTime = intVar
+	}
+	if os.Getenv("DISPATCHER_THROTTLE_MS") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("DISPATCHER_THROTTLE_MS"))
+		dispatcherConfig.ThrottleTime = intVar
+	}
+
+	return dispatcherConfig
+}
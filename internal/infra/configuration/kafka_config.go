@@ -3,6 +3,7 @@ package configuration
 import(
 	"os"
 	"strconv"
+	"slices"
 
 	"github.com/joho/godotenv"
 	go_core_event "github.com/eliezerraj/go-core/event/kafka" 
@@ -60,6 +61,12 @@ func GetKafkaEnv() (go_core_event.KafkaConfigurations, []string) {
 	if os.Getenv("TOPIC_PIX") !=  "" {
 		list_topics = append(list_topics, os.Getenv("TOPIC_PIX"))
 	}
+	// the topics of the routing table
+	for topic := range parseTopicRoutes(os.Getenv("KAFKA_TOPIC_ROUTES")) {
+		if !slices.Contains(list_topics, topic) {
+			list_topics = append(list_topics, topic)
+		}
+	}
 
 	return kafkaConfigurations, list_topics
 }
\ No newline at end of file
@@ -0,0 +1,33 @@
+package configuration
+
+import(
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/go-worker-webhook/internal/core/model"
+)
+
+func GetPixEnv() model.PixConfig {
+	childLogger.Info().Str("func","GetPixEnv").Send()
+
+	err := godotenv.Load(".env")
+	if err != nil {
+		childLogger.Info().Err(err).Send()
+	}
+
+	// defaults
+	pixConfig := model.PixConfig{
+		ReceiverMode: "PAYER",
+		ReceiverKey: "ACCOUNT",
+	}
+
+	if os.Getenv("PIX_RECEIVER_MODE") !=  "" {
+		pixConfig.ReceiverMode = strings.ToUpper(os.Getenv("PIX_RECEIVER_MODE"))
+	}
+	if os.Getenv("PIX_RECEIVER_KEY") !=  "" {
+		pixConfig.ReceiverKey = strings.ToUpper(os.Getenv("PIX_RECEIVER_KEY"))
+	}
+
+	return pixConfig
+}
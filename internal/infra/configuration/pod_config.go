@@ -41,6 +41,13 @@ func GetInfoPod() (	model.InfoPod) {
 	if os.Getenv("ENV") !=  "" {	
 		infoPod.Env = os.Getenv("ENV")
 	}
+
+	// Get hostname (unique per replica, used as lease owner)
+	infoPod.Hostname, err = os.Hostname()
+	if err != nil {
+		childLogger.Error().Err(err).Send()
+		infoPod.Hostname = infoPod.PodName
+	}
 	
 	// Get IP
 	addrs, err := net.InterfaceAddrs()
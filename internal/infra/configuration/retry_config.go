@@ -0,0 +1,40 @@
+package configuration
+
+import(
+	"os"
+	"strconv"
+
+	"github.com/joho/godotenv"
+	"github.com/go-worker-webhook/internal/core/model"
+)
+
+func GetRetryEnv() model.RetryPolicy {
+	childLogger.Info().Str("func","GetRetryEnv").Send()
+
+	err := godotenv.Load(".env")
+	if err != nil {
+		childLogger.Info().Err(err).Send()
+	}
+
+	// defaults
+	retryPolicy := model.RetryPolicy{
+		MaxAttempts: 8,
+		BaseDelay: 5000,
+		MaxDelay: 3600000,
+	}
+
+	if os.Getenv("RETRY_MAX_ATTEMPTS") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("RETRY_MAX_ATTEMPTS"))
+		retryPolicy.MaxAttempts = intVar
+	}
+	if os.Getenv("RETRY_BASE_DELAY_MS") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("RETRY_BASE_DELAY_MS"))
+		retryPolicy.BaseDelay = intVar
+	}
+	if os.Getenv("RETRY_MAX_DELAY_MS") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("RETRY_MAX_DELAY_MS"))
+		retryPolicy.MaxDelay = intVar
+	}
+
+	return retryPolicy
+}
@@ -0,0 +1,48 @@
+package configuration
+
+import(
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/go-worker-webhook/internal/core/model"
+)
+
+// About parse a routing table topic=type,topic=type
+func parseTopicRoutes(value string) map[string]string {
+	routes := make(map[string]string)
+	for _, route := range strings.Split(value, ",") {
+		topic, eventType, ok := strings.Cut(strings.TrimSpace(route), "=")
+		if !ok || strings.TrimSpace(topic) == "" || strings.TrimSpace(eventType) == "" {
+			continue
+		}
+		routes[strings.TrimSpace(topic)] = strings.TrimSpace(eventType)
+	}
+	return routes
+}
+
+func GetTopicRoutingEnv() model.TopicRouting {
+	childLogger.Info().Str("func","GetTopicRoutingEnv").Send()
+
+	err := godotenv.Load(".env")
+	if err != nil {
+		childLogger.Info().Err(err).Send()
+	}
+
+	// defaults (the header override is opt-in, any producer could redirect an event to the receivers of another type)
+	topicRouting := model.TopicRouting{
+		Routes: parseTopicRoutes(os.Getenv("KAFKA_TOPIC_ROUTES")),
+	}
+
+	// the pix topic keeps its type when not in the routing table
+	if os.Getenv("TOPIC_PIX") !=  "" {
+		if _, ok := topicRouting.Routes[os.Getenv("TOPIC_PIX")]; !ok {
+			topicRouting.Routes[os.Getenv("TOPIC_PIX")] = "TOPIC:PIX"
+		}
+	}
+	if os.Getenv("KAFKA_TYPE_HEADER") !=  "" {
+		topicRouting.TypeHeader = os.Getenv("KAFKA_TYPE_HEADER")
+	}
+
+	return topicRouting
+}
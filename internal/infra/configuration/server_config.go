@@ -0,0 +1,52 @@
+package configuration
+
+import(
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/go-worker-webhook/internal/core/model"
+)
+
+func GetHttpServerEnv() model.Server {
+	childLogger.Info().Str("func","GetHttpServerEnv").Send()
+
+	err := godotenv.Load(".env")
+	if err != nil {
+		childLogger.Info().Err(err).Send()
+	}
+
+	var server	model.Server
+	server.Port = 6003
+	server.ReadTimeout = 60
+	server.WriteTimeout = 60
+	server.IdleTimeout = 60
+	server.CtxTimeout = 60
+	server.AdminPort = 6004
+
+	if os.Getenv("PORT") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("PORT"))
+		server.Port = intVar
+	}
+	if os.Getenv("ADMIN_PORT") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("ADMIN_PORT"))
+		server.AdminPort = intVar
+	}
+
+	// Get the admin tokens (one identity:token per line), without them the admin endpoints refuse every request
+	server.AdminTokens = make(map[string]string)
+	file_tokens, err := os.ReadFile("/var/pod/secret/admin_tokens")
+	if err != nil {
+		childLogger.Warn().Err(err).Msg("admin endpoints disabled")
+	}
+	for _, line := range strings.Split(string(file_tokens), "\n") {
+		identity, token, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok || identity == "" || token == "" {
+			continue
+		}
+		server.AdminTokens[token] = identity
+	}
+
+	return server
+}
@@ -0,0 +1,55 @@
+package configuration
+
+import(
+	"os"
+	"strconv"
+	"strings"
+	"encoding/base64"
+
+	"github.com/joho/godotenv"
+	"github.com/go-worker-webhook/internal/core/model"
+)
+
+func GetSigningEnv() model.SigningConfig {
+	childLogger.Info().Str("func","GetSigningEnv").Send()
+
+	err := godotenv.Load(".env")
+	if err != nil {
+		childLogger.Info().Err(err).Send()
+	}
+
+	// defaults
+	signingConfig := model.SigningConfig{
+		KeyPublishTime: 86400,
+		KeyCacheTime: 60,
+		JWKSMaxAge: 60,
+	}
+
+	if os.Getenv("SIGNING_KEY_PUBLISH_SEC") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("SIGNING_KEY_PUBLISH_SEC"))
+		signingConfig.KeyPublishTime = intVar
+	}
+	if os.Getenv("SIGNING_KEY_CACHE_SEC") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("SIGNING_KEY_CACHE_SEC"))
+		signingConfig.KeyCacheTime = intVar
+	}
+	if os.Getenv("SIGNING_JWKS_MAX_AGE_SEC") !=  "" {
+		intVar, _ := strconv.Atoi(os.Getenv("SIGNING_JWKS_MAX_AGE_SEC"))
+		signingConfig.JWKSMaxAge = intVar
+	}
+
+	// Get the key that encrypts the ed25519 private keys (base64 of 32 bytes), without it no signing key is created
+	file_key, err := os.ReadFile("/var/pod/secret/signing_key_encryption_key")
+	if err != nil {
+		childLogger.Warn().Err(err).Msg("ed25519 signing keys disabled")
+		return signingConfig
+	}
+	encryptionKey, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(file_key)))
+	if err != nil || len(encryptionKey) != 32 {
+		childLogger.Error().Err(err).Msg("invalid signing_key_encryption_key, ed25519 signing keys disabled")
+		return signingConfig
+	}
+	signingConfig.EncryptionKey = encryptionKey
+
+	return signingConfig
+}
@@ -0,0 +1,321 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/go-worker-webhook/internal/core/service"
+	"github.com/go-worker-webhook/internal/core/model"
+	"github.com/go-worker-webhook/internal/core/erro"
+)
+
+var childLogger = log.With().Str("component","go-worker-webhook").Str("package","internal.infra.dispatcher").Logger()
+
+type Dispatcher struct {
+	workerService 		*service.WorkerService
+	dispatcherConfig	*model.DispatcherConfig
+	queue				chan model.WebHook
+	mutex				sync.Mutex
+	inFlight			map[int]bool
+	goneConfigs			map[int]time.Time
+	throttledHosts		map[string]time.Time
+	buckets				map[int]*tokenBucket
+	inFlightConfigs		map[int]int
+	owner				string
+}
+
+// About create a dispatcher
+func NewDispatcher(workerService *service.WorkerService, dispatcherConfig *model.DispatcherConfig) *Dispatcher {
+	childLogger.Info().Str("func","NewDispatcher").Interface("dispatcherConfig", dispatcherConfig).Send()
+
+	if dispatcherConfig.WorkerCount < 1 {
+		dispatcherConfig.WorkerCount = 1
+	}
+	if dispatcherConfig.QueueDepth < 1 {
+		dispatcherConfig.QueueDepth = 1
+	}
+	if dispatcherConfig.BatchSize < 1 {
+		dispatcherConfig.BatchSize = 1
+	}
+	if dispatcherConfig.PollInterval < 1 {
+		dispatcherConfig.PollInterval = 1000
+	}
+	if dispatcherConfig.LeaseTime < 1 {
+		dispatcherConfig.LeaseTime = 60
+	}
+	if dispatcherConfig.ThrottleTime < 1 {
+		dispatcherConfig.ThrottleTime = 30000
+	}
+
+	return &Dispatcher{
+		workerService: workerService,
+		dispatcherConfig: dispatcherConfig,
+		queue: make(chan model.WebHook, dispatcherConfig.QueueDepth),
+		inFlight: make(map[int]bool),
+		goneConfigs: make(map[int]time.Time),
+		throttledHosts: make(map[string]time.Time),
+		buckets: make(map[int]*tokenBucket),
+		inFlightConfigs: make(map[int]int),
+	}
+}
+
+// About start the poller and the pool of workers that send the webhooks
+func (d *Dispatcher) Start(ctx context.Context, appServer *model.AppServer, wg *sync.WaitGroup) {
+	childLogger.Info().Str("func","Start").Send()
+
+	defer func() {
+		childLogger.Info().Msg("**** closing Dispatcher() waiting please !!!")
+		defer wg.Done()
+	}()
+
+	// the lease owner must be unique per replica
+	d.owner = appServer.InfoPod.Hostname
+
+	var wg_workers sync.WaitGroup
+	for i := 0; i < d.dispatcherConfig.WorkerCount; i++ {
+		wg_workers.Add(1)
+		go d.worker(ctx, i, &wg_workers)
+	}
+
+	d.poller(ctx)
+
+	close(d.queue)
+	wg_workers.Wait()
+}
+
+// About claim batches of webhook due for sending and fill the queue
+func (d *Dispatcher) poller(ctx context.Context) {
+	childLogger.Info().Str("func","poller").Str("owner", d.owner).Send()
+
+	webhook := model.WebHook{ClaimedBy: d.owner}
+	pollInterval := time.Duration(d.dispatcherConfig.PollInterval) * time.Millisecond
+	wait := time.Duration(0)
+
+	for {
+		select {
+		case <-ctx.Done():
+			childLogger.Info().Msg("**** Poller Shutting !!!")
+			return
+		case <-time.After(wait):
+		}
+		wait = pollInterval
+
+		// only fetch what fits in the queue
+		limit := d.dispatcherConfig.QueueDepth - len(d.queue)
+		if limit > d.dispatcherConfig.BatchSize {
+			limit = d.dispatcherConfig.BatchSize
+		}
+		if limit <= 0 {
+			continue
+		}
+
+		res_webhook_list, err := d.workerService.ClaimWebHooks(ctx, &webhook, d.dispatcherConfig.LeaseTime, limit)
+		if err != nil {
+			childLogger.Error().Err(err).Send()
+			continue
+		}
+
+		queued := 0
+		for _, res_webhook := range fairOrder(*res_webhook_list) {
+			// skip the webhook already queued or being sent
+			if !d.acquire(res_webhook.ID) {
+				continue
+			}
+			select {
+			case d.queue <- res_webhook:
+				queued++
+			case <-ctx.Done():
+				d.giveBack(&res_webhook)
+				d.release(res_webhook.ID)
+				return
+			}
+		}
+
+		childLogger.Debug().Int("fetched", len(*res_webhook_list)).Int("queued", queued).Msg("====> DISPATCHING ...")
+
+		// a full batch means there is backlog, poll again right away
+		if queued > 0 && len(*res_webhook_list) == limit {
+			wait = 0
+		}
+	}
+}
+
+// About send the webhook from the queue
+func (d *Dispatcher) worker(ctx context.Context, id int, wg *sync.WaitGroup) {
+	childLogger.Info().Str("func","worker").Int("worker", id).Send()
+
+	defer wg.Done()
+
+	for webhook := range d.queue {
+		if ctx.Err() != nil || d.isGone(webhook.ConfigID) {
+			d.giveBack(&webhook)
+		} else if until, throttled := d.isThrottled(webhook.Host); throttled {
+			// the receiver asked to slow down, postpone without spending an attempt
+			d.postpone(&webhook, until)
+		} else {
+			d.deliver(ctx, id, &webhook)
+		}
+		d.release(webhook.ID)
+	}
+}
+
+// About send a webhook under the rate limit of its webhook_config and the circuit breaker of its host
+func (d *Dispatcher) deliver(ctx context.Context, id int, webhook *model.WebHook) {
+	until, admitted := d.admit(webhook)
+	if !admitted {
+		// over the rate limit or max in flight, postpone without spending an attempt
+		d.postpone(webhook, until)
+		return
+	}
+	defer d.done(webhook)
+
+	// the webhook may have waited in the queue beyond its lease, renew it right before the http call
+	if !d.renew(ctx, webhook) {
+		return
+	}
+
+	until, allowed := d.workerService.AllowDelivery(webhook.Host)
+	if !allowed {
+		// the host is failing (circuit breaker open), postpone until the next probe
+		d.postpone(webhook, until)
+		return
+	}
+
+	_, err := d.workerService.SendWebHook(ctx, webhook)
+	d.handleError(id, webhook, err)
+}
+
+// About renew the lease of a webhook, a webhook whose lease was lost is dropped (another replica may send it)
+func (d *Dispatcher) renew(ctx context.Context, webhook *model.WebHook) bool {
+	renewed, err := d.workerService.RenewWebHook(ctx, webhook, d.dispatcherConfig.LeaseTime)
+	if err != nil {
+		childLogger.Error().Err(err).Int("webhook", webhook.ID).Msg("error renew lease")
+		return false
+	}
+	if !renewed {
+		childLogger.Warn().Int("webhook", webhook.ID).Str("owner", d.owner).Msg("LEASE EXPIRED, WEBHOOK DROPPED !!!")
+	}
+	return renewed
+}
+
+// About postpone a webhook (release the lease with a new next attempt)
+func (d *Dispatcher) postpone(webhook *model.WebHook, until time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+
+	err := d.workerService.DeferWebHook(ctx, webhook, until)
+	if err != nil {
+		childLogger.Error().Err(err).Int("webhook", webhook.ID).Msg("error postpone webhook")
+	}
+}
+
+// About act on the delivery error
+func (d *Dispatcher) handleError(id int, webhook *model.WebHook, err error) {
+	if err == nil {
+		return
+	}
+
+	var deliveryError *erro.DeliveryError
+	if !errors.As(err, &deliveryError) {
+		// not recorded, the webhook will be claimed again when the lease expires
+		childLogger.Error().Err(err).Int("worker", id).Int("webhook", webhook.ID).Send()
+		return
+	}
+
+	switch {
+	case errors.Is(err, erro.ErrEndpointGone):
+		// the webhook_config was disabled, give back the queued webhooks of it
+		d.markGone(webhook.ConfigID)
+		childLogger.Error().Err(err).Str("class", deliveryError.Class()).Int("worker", id).Int("webhook", webhook.ID).Send()
+	case errors.Is(err, erro.ErrThrottled):
+		// slow down every delivery to the host
+		throttleTime := deliveryError.RetryAfter
+		if throttleTime <= 0 {
+			throttleTime = time.Duration(d.dispatcherConfig.ThrottleTime) * time.Millisecond
+		}
+		d.throttle(webhook.Host, time.Now().Add(throttleTime))
+		childLogger.Warn().Err(err).Str("class", deliveryError.Class()).Str("host", webhook.Host).Dur("throttle", throttleTime).Int("worker", id).Int("webhook", webhook.ID).Send()
+	case errors.Is(err, erro.ErrPermanent):
+		childLogger.Error().Err(err).Str("class", deliveryError.Class()).Int("worker", id).Int("webhook", webhook.ID).Send()
+	default:
+		childLogger.Warn().Err(err).Str("class", deliveryError.Class()).Int("worker", id).Int("webhook", webhook.ID).Send()
+	}
+}
+
+// About hold the deliveries to a host until a time
+func (d *Dispatcher) throttle(host string, until time.Time) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if until.After(d.throttledHosts[host]) {
+		d.throttledHosts[host] = until
+	}
+}
+
+// About check a host is throttled, and until when
+func (d *Dispatcher) isThrottled(host string) (time.Time, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	until, ok := d.throttledHosts[host]
+	if ok && time.Now().After(until) {
+		delete(d.throttledHosts, host)
+		return until, false
+	}
+	return until, ok
+}
+
+// About remember a disabled webhook_config (for a lease time, then the claim filter takes over)
+func (d *Dispatcher) markGone(configID int) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.goneConfigs[configID] = time.Now().Add(time.Duration(d.dispatcherConfig.LeaseTime) * time.Second)
+}
+
+// About check a webhook_config was disabled
+func (d *Dispatcher) isGone(configID int) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	until, ok := d.goneConfigs[configID]
+	if ok && time.Now().After(until) {
+		delete(d.goneConfigs, configID)
+		return false
+	}
+	return ok
+}
+
+// About release the lease of a webhook not sent (shutting down), so another replica can claim it at once
+func (d *Dispatcher) giveBack(webhook *model.WebHook) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+
+	err := d.workerService.ReleaseWebHook(ctx, webhook)
+	if err != nil {
+		childLogger.Error().Err(err).Int("webhook", webhook.ID).Msg("error release lease")
+	}
+}
+
+// About mark a webhook as in flight
+func (d *Dispatcher) acquire(id int) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.inFlight[id] {
+		return false
+	}
+	d.inFlight[id] = true
+	return true
+}
+
+// About unmark a webhook as in flight
+func (d *Dispatcher) release(id int) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	delete(d.inFlight, id)
+}
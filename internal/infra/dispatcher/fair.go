@@ -0,0 +1,39 @@
+package dispatcher
+
+import (
+	"github.com/go-worker-webhook/internal/core/model"
+)
+
+// About order the webhooks in weighted round robin per webhook_config (the key of the weight)
+// Each round takes up to the weight of each webhook_config, keeping the order inside a webhook_config
+func fairOrder(webhooks []model.WebHook) []model.WebHook {
+	configs := []int{}
+	queues := make(map[int][]model.WebHook)
+	for _, webhook := range webhooks {
+		if _, ok := queues[webhook.ConfigID]; !ok {
+			configs = append(configs, webhook.ConfigID)
+		}
+		queues[webhook.ConfigID] = append(queues[webhook.ConfigID], webhook)
+	}
+
+	ordered := make([]model.WebHook, 0, len(webhooks))
+	for len(ordered) < len(webhooks) {
+		for _, config := range configs {
+			queue := queues[config]
+			if len(queue) == 0 {
+				continue
+			}
+			weight := queue[0].Weight
+			if weight < 1 {
+				weight = 1
+			}
+			if weight > len(queue) {
+				weight = len(queue)
+			}
+			ordered = append(ordered, queue[:weight]...)
+			queues[config] = queue[weight:]
+		}
+	}
+
+	return ordered
+}
@@ -0,0 +1,75 @@
+package dispatcher
+
+import (
+	"time"
+
+	"github.com/go-worker-webhook/internal/core/model"
+)
+
+// token bucket of a webhook_config
+type tokenBucket struct {
+	tokens		float64
+	last		time.Time
+}
+
+// About admit a webhook under the rate limit and the max in flight of its webhook_config
+// When not admitted, returns when it should be tried again
+func (d *Dispatcher) admit(webhook *model.WebHook) (time.Time, bool) {
+	rateLimit := webhook.RateLimit
+	if rateLimit == nil {
+		return time.Time{}, true
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	now := time.Now()
+
+	if rateLimit.MaxInFlight > 0 && d.inFlightConfigs[webhook.ConfigID] >= rateLimit.MaxInFlight {
+		return now.Add(time.Duration(d.dispatcherConfig.PollInterval) * time.Millisecond), false
+	}
+
+	if rateLimit.RPS > 0 {
+		burst := float64(rateLimit.Burst)
+		if burst < 1 {
+			burst = 1
+		}
+
+		bucket, ok := d.buckets[webhook.ConfigID]
+		if !ok {
+			bucket = &tokenBucket{tokens: burst, last: now}
+			d.buckets[webhook.ConfigID] = bucket
+		}
+
+		// refill since the last request
+		bucket.tokens = bucket.tokens + now.Sub(bucket.last).Seconds() * rateLimit.RPS
+		if bucket.tokens > burst {
+			bucket.tokens = burst
+		}
+		bucket.last = now
+
+		if bucket.tokens < 1 {
+			wait := time.Duration((1 - bucket.tokens) / rateLimit.RPS * float64(time.Second))
+			return now.Add(wait), false
+		}
+		bucket.tokens = bucket.tokens - 1
+	}
+
+	d.inFlightConfigs[webhook.ConfigID]++
+	return time.Time{}, true
+}
+
+// About finish a webhook admitted
+func (d *Dispatcher) done(webhook *model.WebHook) {
+	if webhook.RateLimit == nil {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.inFlightConfigs[webhook.ConfigID]--
+	if d.inFlightConfigs[webhook.ConfigID] <= 0 {
+		delete(d.inFlightConfigs, webhook.ConfigID)
+	}
+}
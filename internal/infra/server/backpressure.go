@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/go-worker-webhook/internal/core/model"
+)
+
+// About pause the consumer while the pending webhooks are above the high-water mark
+// and resume it below the low-water mark
+func (s *ServerWorker) backpressure(ctx context.Context, backpressureConfig *model.BackpressureConfig) {
+	childLogger.Info().Str("func","backpressure").Interface("backpressureConfig", backpressureConfig).Send()
+
+	if backpressureConfig.HighWater <= 0 {
+		return
+	}
+
+	var pending, paused atomic.Int64
+	s.registerBackpressureMetrics(&pending, &paused)
+
+	checkInterval := time.Duration(backpressureConfig.CheckInterval) * time.Millisecond
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(checkInterval):
+		}
+
+		count, err := s.workerService.CountPendingWebHooks(ctx)
+		if err != nil {
+			childLogger.Error().Err(err).Msg("error count pending webhooks")
+			continue
+		}
+		pending.Store(count)
+
+		switch {
+		case paused.Load() == 0 && count >= int64(backpressureConfig.HighWater):
+			childLogger.Warn().Int64("pending", count).Int("high_water", backpressureConfig.HighWater).Msg("CONSUMER PAUSED (BACKPRESSURE) !!!")
+			paused.Store(1)
+		case paused.Load() == 1 && count <= int64(backpressureConfig.LowWater):
+			err = s.workerEvent.Resume()
+			if err != nil {
+				childLogger.Error().Err(err).Msg("error resume consumer")
+				continue
+			}
+			childLogger.Info().Int64("pending", count).Int("low_water", backpressureConfig.LowWater).Msg("CONSUMER RESUMED !!!")
+			paused.Store(0)
+			continue
+		}
+
+		// while paused the assignment is paused again (partitions assigned by a rebalance)
+		if paused.Load() == 1 {
+			err = s.workerEvent.Pause()
+			if err != nil {
+				childLogger.Error().Err(err).Msg("error pause consumer")
+			}
+		}
+	}
+}
+
+// About expose the pending webhooks and the paused state of the consumer
+func (s *ServerWorker) registerBackpressureMetrics(pending *atomic.Int64, paused *atomic.Int64) {
+	meter := otel.Meter("go-worker-webhook")
+
+	_, err := meter.Int64ObservableGauge("webhook.pending",
+		metric.WithDescription("webhooks pending delivery"),
+		metric.WithInt64Callback(func(ctx context.Context, observer metric.Int64Observer) error {
+			observer.Observe(pending.Load())
+			return nil
+		}))
+	if err != nil {
+		childLogger.Error().Err(err).Msg("error register pending metric")
+	}
+
+	_, err = meter.Int64ObservableGauge("webhook.consumer.paused",
+		metric.WithDescription("consumer paused by backpressure (0 running, 1 paused)"),
+		metric.WithInt64Callback(func(ctx context.Context, observer metric.Int64Observer) error {
+			observer.Observe(paused.Load())
+			return nil
+		}))
+	if err != nil {
+		childLogger.Error().Err(err).Msg("error register paused metric")
+	}
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-worker-webhook/internal/adapter/event"
+	"github.com/go-worker-webhook/internal/core/model"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// About drain up to BatchSize messages or BatchTimeout after the first one
+// Returns false when the channel is closed (shutting down)
+func (s *ServerWorker) nextBatch(messages <-chan event.Message, offsets *offsetTracker, consumerConfig *model.ConsumerConfig) ([]event.Message, bool) {
+	batchSize := consumerConfig.BatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	batch := []event.Message{}
+	var timeout <-chan time.Time
+	for len(batch) < batchSize {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				return batch, false
+			}
+			if offsets.stale(msg) {
+				childLogger.Debug().Str("topic", msg.Topic).Int32("partition", msg.Partition).Int64("offset", msg.Offset).Msg("skip message read ahead of a rewound partition")
+				continue
+			}
+			batch = append(batch, msg)
+			// the timeout starts with the first message
+			if timeout == nil {
+				timeout = time.After(time.Duration(consumerConfig.BatchTimeout) * time.Millisecond)
+			}
+		case <-timeout:
+			return batch, true
+		}
+	}
+	return batch, true
+}
+
+// About consume a batch in a single transaction and a single offset commit
+// When the batch fails the messages are consumed one by one (retry and dead-letter of each one)
+func (s *ServerWorker) consumeBatch(ctx context.Context, appServer *model.AppServer, batch []event.Message, offsets *offsetTracker) {
+	if len(batch) > 1 {
+		err := s.processBatch(ctx, appServer, batch)
+		if err == nil {
+			err = s.workerEvent.CommitBatch(batch)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				// the webhooks are recorded, reading the messages again is a no-op (idempotency)
+				childLogger.Error().Err(err).Int("messages", len(batch)).Msg("error commit batch, rewinding the partitions")
+				s.rewind(ctx, appServer, firstOfPartitions(batch), offsets)
+				return
+			}
+			childLogger.Info().Int("messages", len(batch)).Msg("COMMIT BATCH!!!!")
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		childLogger.Warn().Err(err).Int("messages", len(batch)).Msg("error process batch, consuming one by one")
+	}
+
+	for _, msg := range batch {
+		// a message of the partition was rewound, the next ones are read again
+		if offsets.stale(msg) {
+			continue
+		}
+		s.consumeMessage(ctx, appServer, msg, offsets)
+	}
+}
+
+// About process a batch, creating the webhooks of all the events in one transaction
+// The span of the batch is linked to the trace of each message, the trace ids of the messages are kept in the context
+func (s *ServerWorker) processBatch(ctx context.Context, appServer *model.AppServer, batch []event.Message) error {
+	childLogger.Info().Str("func","processBatch").Int("messages", len(batch)).Send()
+
+	list_webhook := []model.WebHook{}
+	list_trace_id := []string{}
+	links := []trace.Link{}
+	for _, msg := range batch {
+		webHook, err := webHookOf(appServer, msg)
+		if err != nil {
+			return err
+		}
+		list_webhook = append(list_webhook, *webHook)
+
+		if msg.Header["trace-request-id"] != "" {
+			list_trace_id = append(list_trace_id, msg.Header["trace-request-id"])
+		}
+		link := trace.LinkFromContext(traceContextOf(ctx, msg),
+									attribute.String("trace-request-id", msg.Header["trace-request-id"]),
+									attribute.Int64("kafka-offset", msg.Offset))
+		if link.SpanContext.IsValid() {
+			links = append(links, link)
+		}
+	}
+
+	// without a traceid in the messages it will be created
+	ctx = setContextTraceId(ctx, strings.Join(list_trace_id, ","))
+	ctx, span := tracer.Start(ctx, appServer.InfoPod.PodName,
+							trace.WithLinks(links...),
+							trace.WithAttributes(attribute.StringSlice("trace-request-ids", list_trace_id)))
+	defer span.End()
+
+	// call service
+	_, err := s.workerService.InsertWebHooks(ctx, list_webhook)
+	if err != nil {
+		childLogger.Error().Err(err).Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+		return err
+	}
+
+	return nil
+}
+
+// About the first message of each partition of a batch
+func firstOfPartitions(batch []event.Message) []event.Message {
+	seen := make(map[partitionKey]bool)
+	first := []event.Message{}
+	for _, msg := range batch {
+		key := partitionKey{msg.Topic, msg.Partition}
+		if !seen[key] {
+			seen[key] = true
+			first = append(first, msg)
+		}
+	}
+	return first
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/go-worker-webhook/internal/adapter/api"
+	"github.com/go-worker-webhook/internal/core/model"
+)
+
+type HttpServer struct {
+	httpServer	*model.Server
+}
+
+// About create a http server (health and admin endpoints)
+func NewHttpAppServer(httpServer *model.Server) HttpServer {
+	childLogger.Info().Str("func","NewHttpAppServer").Send()
+
+	return HttpServer{httpServer: httpServer }
+}
+
+// About set a trace-request-id in every request
+func middleWareTraceId(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		trace_id := r.Header.Get("X-Request-Id")
+		if trace_id == "" {
+			trace_id = uuid.New().String()
+		}
+		ctx := context.WithValue(r.Context(), "trace-request-id", trace_id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// About authenticate the admin requests (Authorization: Bearer <token>), the identity of the token is the actor
+func middleWareAdminAuth(adminTokens map[string]string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		identity := ""
+		for adminToken, adminIdentity := range adminTokens {
+			if ok && subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1 {
+				identity = adminIdentity
+			}
+		}
+		if identity == "" {
+			childLogger.Warn().Str("path", r.URL.Path).Str("remote", r.RemoteAddr).Msg("admin request unauthorized")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		ctx := context.WithValue(r.Context(), "admin-identity", identity)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// About start the http servers, the public one (health and jwks) and the internal admin one
+func (h HttpServer) StartHttpAppServer(	ctx context.Context, 
+										httpRouters *api.HttpRouters,
+										appServer *model.AppServer,
+										wg *sync.WaitGroup) {
+	childLogger.Info().Str("func","StartHttpAppServer").Send()
+
+	defer func() {
+		childLogger.Info().Msg("**** closing StartHttpAppServer() waiting please !!!")
+		defer wg.Done()
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", httpRouters.Health)
+	mux.HandleFunc("GET /.well-known/jwks.json", httpRouters.GetJWKS)
+
+	// the admin endpoints are not exposed with the public ones
+	adminMux := http.NewServeMux()
+	adminMux.HandleFunc("POST /admin/webhooks/replay", httpRouters.ReplayWebHooks)
+	adminMux.HandleFunc("GET /admin/webhooks/{id}/attempts", httpRouters.ListDeliveryAttempts)
+	adminMux.HandleFunc("GET /admin/circuit-breakers", httpRouters.GetCircuitBreakers)
+	adminMux.HandleFunc("POST /admin/signing-keys/rotate", httpRouters.RotateSigningKey)
+
+	if len(h.httpServer.AdminTokens) == 0 {
+		childLogger.Warn().Msg("there isnt admin tokens, every admin request will be refused")
+	}
+
+	srv := h.newServer(h.httpServer.Port, middleWareTraceId(mux))
+	adminSrv := h.newServer(h.httpServer.AdminPort, middleWareTraceId(middleWareAdminAuth(h.httpServer.AdminTokens, adminMux)))
+
+	childLogger.Info().Str("Service Port", strconv.Itoa(h.httpServer.Port)).Str("Admin Port", strconv.Itoa(h.httpServer.AdminPort)).Send()
+
+	for _, res_srv := range []*http.Server{srv, adminSrv} {
+		go func() {
+			err := res_srv.ListenAndServe()
+			if err != nil && err != http.ErrServerClosed {
+				childLogger.Error().Err(err).Str("addr", res_srv.Addr).Msg("canceling http server !!!")
+			}
+		}()
+	}
+
+	<-ctx.Done()
+
+	ctxShutdown, cancel := context.WithTimeout(context.Background(), time.Duration(h.httpServer.CtxTimeout) * time.Second)
+	defer cancel()
+
+	for _, res_srv := range []*http.Server{srv, adminSrv} {
+		if err := res_srv.Shutdown(ctxShutdown); err != nil && err != http.ErrServerClosed {
+			childLogger.Error().Err(err).Str("addr", res_srv.Addr).Msg("warning dirty shutdown !!!")
+		}
+	}
+}
+
+// About create a http server on a port
+func (h HttpServer) newServer(port int, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         ":" + strconv.Itoa(port),
+		Handler:      handler,
+		ReadTimeout:  time.Duration(h.httpServer.ReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(h.httpServer.WriteTimeout) * time.Second,
+		IdleTimeout:  time.Duration(h.httpServer.IdleTimeout) * time.Second, 
+	}
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+
+	"github.com/go-worker-webhook/internal/core/model"
+)
+
+// About create the meter provider, the metrics go to the same otlp collector of the traces
+// Without a collector the metrics stay in the no-op provider
+func newMeterProvider(ctx context.Context, appServer *model.AppServer) *sdkmetric.MeterProvider {
+	childLogger.Info().Str("func","newMeterProvider").Send()
+
+	if !appServer.ConfigOTEL.UseOtlpCollector || appServer.ConfigOTEL.OtelExportEndpoint == "" {
+		childLogger.Warn().Msg("there isnt otlp collector, metrics not exported")
+		return nil
+	}
+
+	exporter, err := otlpmetricgrpc.New(ctx, 
+										otlpmetricgrpc.WithEndpoint(appServer.ConfigOTEL.OtelExportEndpoint),
+										otlpmetricgrpc.WithInsecure())
+	if err != nil {
+		childLogger.Error().Err(err).Msg("error create metric exporter")
+		return nil
+	}
+
+	res := resource.NewSchemaless(	attribute.String("service.name", appServer.InfoPod.PodName),
+									attribute.String("service.version", appServer.InfoPod.ApiVersion),
+									attribute.String("deployment.environment", appServer.InfoPod.Env))
+
+	return sdkmetric.NewMeterProvider(	sdkmetric.WithResource(res),
+										sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(10 * time.Second))))
+}
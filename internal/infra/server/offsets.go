@@ -0,0 +1,60 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/go-worker-webhook/internal/adapter/event"
+)
+
+type partitionKey struct {
+	topic		string
+	partition	int32
+}
+
+// offsetTracker keeps the partitions rewound to a message not finished
+// The messages read after it (already fetched before the seek) are skipped, they come again after it
+// It is reset by the rebalance callback (consumer goroutine), so it is guarded by a mutex
+type offsetTracker struct {
+	mu			sync.Mutex
+	rewound		map[partitionKey]int64
+}
+
+// About create an offset tracker
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{rewound: make(map[partitionKey]int64)}
+}
+
+// About rewind the partition of the message to it
+func (o *offsetTracker) rewind(msg event.Message) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.rewound[partitionKey{msg.Topic, msg.Partition}] = msg.Offset
+}
+
+// About check a message was read ahead of a rewound message (it must be skipped)
+func (o *offsetTracker) stale(msg event.Message) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	key := partitionKey{msg.Topic, msg.Partition}
+	offset, ok := o.rewound[key]
+	if !ok {
+		return false
+	}
+	if msg.Offset > offset {
+		return true
+	}
+	// the rewound message is back (or an older one after a rebalance)
+	delete(o.rewound, key)
+	return false
+}
+
+// About forget the rewind of a partition revoked or assigned in a rebalance
+// The consumer reads it from the committed offset, a message after the rewound one is not skipped
+func (o *offsetTracker) reset(topic string, partition int32) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	delete(o.rewound, partitionKey{topic, partition})
+}
@@ -2,37 +2,57 @@ package server
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
 	"time"
 	"sync"
 	"encoding/json"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/rs/zerolog/log"
-	"github.com/go-worker-webhook/internal/core/service"
 	"github.com/go-worker-webhook/internal/adapter/event"
 	"github.com/go-worker-webhook/internal/core/model"
+	"github.com/go-worker-webhook/internal/core/erro"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/trace"
 	"go.opentelemetry.io/otel/propagation"
 	//"go.opentelemetry.io/contrib/propagators/aws/xray"
 
-	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
-
 	go_core_observ "github.com/eliezerraj/go-core/observability"
-	go_core_event "github.com/eliezerraj/go-core/event/kafka" 
 )
 
 var childLogger = log.With().Str("component","go-worker-webhook").Str("package","internal.infra.server").Logger()
 
 var tracerProvider go_core_observ.TracerProvider
-var consumerWorker go_core_event.ConsumerWorker
 var infoTrace go_core_observ.InfoTrace
 var tracer 			trace.Tracer
 
 type ServerWorker struct {
-	workerService 	*service.WorkerService
-	workerEvent 	*event.WorkerEvent
+	workerService 	WebHookWriter
+	workerEvent 	EventConsumer
+}
+
+// WebHookWriter is the service creating the webhooks of the events (service.WorkerService)
+type WebHookWriter interface {
+	InsertWebHook(ctx context.Context, webhook *model.WebHook) (*[]model.WebHook, error)
+	InsertWebHooks(ctx context.Context, webhooks []model.WebHook) (*[]model.WebHook, error)
+	CountPendingWebHooks(ctx context.Context) (int64, error)
+}
+
+// EventConsumer is the kafka consumer used by the worker (event.WorkerEvent)
+type EventConsumer interface {
+	Consumer(ctx context.Context, messages chan<- event.Message)
+	Commit(message event.Message) error
+	CommitBatch(messages []event.Message) error
+	Seek(message event.Message) error
+	Pause() error
+	Resume() error
+	OnRebalance(callback func(topic string, partition int32))
+	DeadLetter(ctx context.Context, message event.Message, reason string, cause error, attempts int, consumer string) error
+	Close()
 }
 
 // Set a trace-i inside the context
@@ -51,7 +71,7 @@ func setContextTraceId(ctx context.Context, trace_id string) context.Context {
 }
 
 // About create a worrker event
-func NewServerWorker(workerService *service.WorkerService, workerEvent *event.WorkerEvent ) *ServerWorker {
+func NewServerWorker(workerService WebHookWriter, workerEvent EventConsumer ) *ServerWorker {
 	childLogger.Info().Str("func","NewServerWorker").Send()
 
 	return &ServerWorker{
@@ -60,7 +80,20 @@ func NewServerWorker(workerService *service.WorkerService, workerEvent *event.Wo
 	}
 }
 
-type kafkaHeaderCarrier []kafka.Header
+// About the event type of a message, the header (when configured) overrides the routing table of the topic
+// A topic not routed keeps the type of the payload
+func routeType(topicRouting *model.TopicRouting, msg event.Message, payloadType string) string {
+	if topicRouting == nil {
+		return payloadType
+	}
+	if topicRouting.TypeHeader != "" && msg.Header[topicRouting.TypeHeader] != "" {
+		return msg.Header[topicRouting.TypeHeader]
+	}
+	if eventType, ok := topicRouting.Routes[msg.Topic]; ok {
+		return eventType
+	}
+	return payloadType
+}
 
 // About consume event kafka
 func (s *ServerWorker) Consumer(ctx context.Context, appServer *model.AppServer, wg *sync.WaitGroup) {
@@ -83,6 +116,12 @@ func (s *ServerWorker) Consumer(ctx context.Context, appServer *model.AppServer,
 		tracer = tp.Tracer(appServer.InfoPod.PodName)
 	}
 
+	// the metrics (circuit breaker, backpressure) are exported by it, also the ones registered before
+	mp := newMeterProvider(ctx, appServer)
+	if mp != nil {
+		otel.SetMeterProvider(mp)
+	}
+
 	// handle defer
 	defer func() {
 		// check otel is on
@@ -92,88 +131,218 @@ func (s *ServerWorker) Consumer(ctx context.Context, appServer *model.AppServer,
 				childLogger.Info().Err(err).Send()
 			}
 		}
+		if mp != nil {
+			err := mp.Shutdown(context.Background())
+			if err != nil{
+				childLogger.Info().Err(err).Send()
+			}
+		}
 		childLogger.Info().Msg("**** closing Consumer() waiting please !!!")
 		defer wg.Done()
 	}()
 
-	messages := make(chan go_core_event.Message)
+	messages := make(chan event.Message)
 
-	go s.workerEvent.WorkerKafka.Consumer(s.workerEvent.Topics, messages)
+	// the offset of a partition never advances past a message not finished
+	// a partition revoked or assigned in a rebalance is read again from its committed offset
+	offsets := newOffsetTracker()
+	s.workerEvent.OnRebalance(offsets.reset)
 
-	for msg := range messages {
-		childLogger.Info().Msg("=============== MSG FROM KAFKA ==================")
-		childLogger.Info().Interface("msg",msg).Send()
-		childLogger.Info().Msg("=============== MSG FROM KAFKA ==================")
-		
-		// Marshall payload	
-		var webHook model.WebHook
-		json.Unmarshal([]byte(msg.Payload), &webHook)
+	go s.workerEvent.Consumer(ctx, messages)
+	defer s.workerEvent.Close()
 
-		// valid the headers, if there isnt a traceid it will be created
-		var header string
-		if (*msg.Header)["trace-request-id"] != "" {
-			header = (*msg.Header)["trace-request-id"]
+	go s.backpressure(ctx, appServer.BackpressureConfig)
+
+	for {
+		batch, open := s.nextBatch(messages, offsets, appServer.ConsumerConfig)
+		if len(batch) > 0 {
+			s.consumeBatch(ctx, appServer, batch, offsets)
+		}
+		if !open {
+			return
 		}
+	}
+}
 
-		ctx = setContextTraceId(ctx, header)
+// About consume a message, committing its offset when finished or rewinding its partition
+func (s *ServerWorker) consumeMessage(ctx context.Context, appServer *model.AppServer, msg event.Message, offsets *offsetTracker) {
+	childLogger.Info().Msg("=============== MSG FROM KAFKA ==================")
+	childLogger.Info().Str("topic", msg.Topic).Int32("partition", msg.Partition).Int64("offset", msg.Offset).Str("payload", string(msg.Payload)).Send()
+	childLogger.Info().Msg("=============== MSG FROM KAFKA ==================")
 
-		//Trace
-		// Convert headers to carrier
-		carrier := propagation.MapCarrier{}
+	err := s.handleMessage(ctx, appServer, msg)
+	if err == nil {
+		err = s.workerEvent.Commit(msg)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			// shutting down, the message is read again after restart
+			return
+		}
+		// rewind the partition to the message, it is read again after a delay
+		childLogger.Error().Err(err).Str("topic", msg.Topic).Int32("partition", msg.Partition).Int64("offset", msg.Offset).Msg("message not finished, rewinding the partition")
+		s.rewind(ctx, appServer, []event.Message{msg}, offsets)
+		return
+	}
+	childLogger.Info().Str("topic", msg.Topic).Int32("partition", msg.Partition).Int64("offset", msg.Offset).Msg("COMMIT!!!!")
+}
 
-		carrier["X-Amzn-Trace-Id"] = (*msg.Header)["X-Amzn-Trace-Id"]
-		carrier["TraceID"] = (*msg.Header)["TraceID"]
-		carrier["SpanID"] = (*msg.Header)["SpanID"]
+// About rewind the partitions to the messages (seek), after a delay
+func (s *ServerWorker) rewind(ctx context.Context, appServer *model.AppServer, msgs []event.Message, offsets *offsetTracker) {
+	for _, msg := range msgs {
+		offsets.rewind(msg)
+	}
 
-		parentCtx := otel.GetTextMapPropagator().Extract(ctx, carrier)
-		ctx, span := tracer.Start(parentCtx, appServer.InfoPod.PodName)
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(time.Duration(appServer.ConsumerConfig.RetryDelay) * time.Millisecond):
+	}
 
-		// call service
-		_, err := s.workerService.InsertWebHook(ctx, &webHook)
+	for _, msg := range msgs {
+		err := s.workerEvent.Seek(msg)
 		if err != nil {
-			childLogger.Error().Err(err).Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
-			childLogger.Info().Interface("trace-resquest-id", ctx.Value("trace-request-id")).Msg("ROLLBACK!!!!")
-		} else {
-			s.workerEvent.WorkerKafka.Commit()
-			childLogger.Info().Interface("trace-resquest-id", ctx.Value("trace-request-id")).Msg("COMMIT!!!!")
+			// the partition stays rewound (nothing is committed) until it is revoked or assigned in a rebalance
+			childLogger.Error().Err(err).Msg("error seek partition")
 		}
-
-		span.End()
 	}
 }
 
-// About check webhook to send
-func (s *ServerWorker) SendWebhook(ctx context.Context, appServer *model.AppServer, wg *sync.WaitGroup) {
-	childLogger.Info().Str("func","SendWebhook").Send()
-
-	defer func() {
-		childLogger.Info().Msg("**** closing SendWebhook() waiting please !!!")
-		defer wg.Done()
-	}()
-
-	webhook := model.WebHook{Status: "IN-QUEUE:WAITING-FOR-SEND"}
-	ts := 300 // sleep time 5min
-
+// About handle a message with a bounded retry, a poison message (malformed or invalid) is not retried
+// When it keeps failing it goes to the dead-letter, unless the failure is of the infrastructure (database down, timeout)
+// the message is valid then, the error is returned and the message is not committed (the partition is rewound)
+func (s *ServerWorker) handleMessage(ctx context.Context, appServer *model.AppServer, msg event.Message) error {
+	attempts := 0
+	var err error
 	for {
+		attempts++
+		err = s.processMessage(ctx, appServer, msg)
+		if err == nil || isPoison(err) || attempts >= appServer.ConsumerConfig.MaxRetry {
+			break
+		}
+		childLogger.Warn().Err(err).Int("attempt", attempts).Msg("error process message, retrying")
+
 		select {
 		case <-ctx.Done():
-			childLogger.Info().Msg("**** Worker Shutting !!!")
-			return
-		case <-time.After(time.Second):
-			childLogger.Debug().Str("func","SendWebhook").Msg("====> SENDING ...")
+			return ctx.Err()
+		case <-time.After(time.Duration(appServer.ConsumerConfig.RetryDelay) * time.Millisecond):
 		}
+	}
+	if err == nil {
+		return nil
+	}
+	if !isPoison(err) && isInfrastructure(err) {
+		childLogger.Error().Err(err).Int("attempts", attempts).Msg("infrastructure error, message not sent to dead-letter")
+		return err
+	}
 
-		// get all webhook waiting fo sending
-		res_webhook, err := s.workerService.GetWebHook(ctx, &webhook)
-		if err != nil {
-			childLogger.Debug().Interface("error",err).Msg("NO WEBHOOK TO SEND !!!")
-		} else {
-			// send the webhook
-			_, err = s.workerService.SendWebHook(ctx, res_webhook)
-			if err != nil {
-				childLogger.Error().Err(err).Interface("error",err).Send()
-			}	
-		}	
-		time.Sleep(time.Duration(ts) *time.Second)
-	}
-}
\ No newline at end of file
+	reason := "RETRY-EXHAUSTED"
+	if isPoison(err) {
+		reason = "POISON"
+	}
+	if appServer.ConsumerConfig.DlqTopic == "" && appServer.ConsumerConfig.DlqSkip {
+		childLogger.Error().Err(err).Str("reason", reason).Int("attempts", attempts).Str("topic", msg.Topic).Int32("partition", msg.Partition).Int64("offset", msg.Offset).Msg("MSG SKIPPED (NO DEAD-LETTER) !!!")
+		return nil
+	}
+	childLogger.Error().Err(err).Str("reason", reason).Int("attempts", attempts).Msg("MSG TO DEAD-LETTER !!!")
+
+	err = s.workerEvent.DeadLetter(ctx, msg, reason, err, attempts, appServer.InfoPod.PodName)
+	if err != nil {
+		childLogger.Error().Err(err).Msg("error send message to dead-letter")
+		return err
+	}
+
+	return nil
+}
+
+// About a message that will never succeed (malformed or invalid), sent to the dead-letter without retry
+func isPoison(err error) bool {
+	return errors.Is(err, erro.ErrUnmarshal) || errors.Is(err, erro.ErrInvalid)
+}
+
+// About an error of the infrastructure (not of the message), the message succeeds when it is back
+func isInfrastructure(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return true
+	}
+	if pgconn.Timeout(err) || pgconn.SafeToRetry(err) {
+		return true
+	}
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// connection exception, transaction rollback (serialization, deadlock), insufficient resources,
+		// operator intervention (shutdown) and system error
+		switch pgErr.Code[:2] {
+		case "08", "40", "53", "57", "58":
+			return true
+		}
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// About the webhook of a message, the event type comes from the routing table (or the header), then the payload
+func webHookOf(appServer *model.AppServer, msg event.Message) (*model.WebHook, error) {
+	// Marshall payload	
+	var webHook model.WebHook
+	err := json.Unmarshal(msg.Payload, &webHook)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", erro.ErrUnmarshal, err)
+	}
+
+	webHook.Topic = msg.Topic
+	webHook.KafkaPartition = msg.Partition
+	webHook.KafkaOffset = msg.Offset
+	webHook.KafkaKey = msg.Key
+	webHook.Type = routeType(appServer.TopicRouting, msg, webHook.Type)
+
+	return &webHook, nil
+}
+
+// About process a message, creating the webhooks of the event
+func (s *ServerWorker) processMessage(ctx context.Context, appServer *model.AppServer, msg event.Message) error {
+	webHook, err := webHookOf(appServer, msg)
+	if err != nil {
+		return err
+	}
+
+	// valid the headers, if there isnt a traceid it will be created
+	var header string
+	if msg.Header["trace-request-id"] != "" {
+		header = msg.Header["trace-request-id"]
+	}
+
+	ctx = setContextTraceId(ctx, header)
+
+	//Trace
+	parentCtx := traceContextOf(ctx, msg)
+	ctx, span := tracer.Start(parentCtx, appServer.InfoPod.PodName)
+	defer span.End()
+
+	// call service
+	_, err = s.workerService.InsertWebHook(ctx, webHook)
+	if err != nil {
+		childLogger.Error().Err(err).Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+		childLogger.Info().Interface("trace-resquest-id", ctx.Value("trace-request-id")).Msg("ROLLBACK!!!!")
+		return err
+	}
+
+	return nil
+}
+
+// About the trace context propagated in the headers of a message
+func traceContextOf(ctx context.Context, msg event.Message) context.Context {
+	// Convert headers to carrier
+	carrier := propagation.MapCarrier{}
+
+	carrier["X-Amzn-Trace-Id"] = msg.Header["X-Amzn-Trace-Id"]
+	carrier["TraceID"] = msg.Header["TraceID"]
+	carrier["SpanID"] = msg.Header["SpanID"]
+
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
@@ -0,0 +1,314 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/go-worker-webhook/internal/adapter/event"
+	"github.com/go-worker-webhook/internal/core/model"
+
+	"go.opentelemetry.io/otel"
+)
+
+// fakeConsumer is an in-memory kafka consumer, it records the commits, seeks and dead-letters
+type fakeConsumer struct {
+	mu				sync.Mutex
+	committed		[]event.Message
+	seeks			[]event.Message
+	deadLetters		[]string
+	deadLetterErr	error
+	rebalanced		func(topic string, partition int32)
+}
+
+func (f *fakeConsumer) Consumer(ctx context.Context, messages chan<- event.Message) {
+	close(messages)
+}
+
+func (f *fakeConsumer) Commit(message event.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.committed = append(f.committed, message)
+	return nil
+}
+
+func (f *fakeConsumer) CommitBatch(messages []event.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.committed = append(f.committed, messages...)
+	return nil
+}
+
+func (f *fakeConsumer) Seek(message event.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seeks = append(f.seeks, message)
+	return nil
+}
+
+func (f *fakeConsumer) Pause() error { return nil }
+
+func (f *fakeConsumer) Resume() error { return nil }
+
+func (f *fakeConsumer) OnRebalance(callback func(topic string, partition int32)) {
+	f.rebalanced = callback
+}
+
+// About simulate a rebalance revoking (or assigning) a partition
+func (f *fakeConsumer) rebalance(topic string, partition int32) {
+	if f.rebalanced != nil {
+		f.rebalanced(topic, partition)
+	}
+}
+
+func (f *fakeConsumer) DeadLetter(ctx context.Context, message event.Message, reason string, cause error, attempts int, consumer string) error {
+	if f.deadLetterErr != nil {
+		return f.deadLetterErr
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deadLetters = append(f.deadLetters, fmt.Sprintf("%v:%v", reason, attempts))
+	return nil
+}
+
+func (f *fakeConsumer) Close() {}
+
+// fakeWriter returns its errors in order, one per call, then succeeds
+type fakeWriter struct {
+	errs		[]error
+	calls		int
+	traceID		interface{}
+}
+
+func (f *fakeWriter) next() error {
+	f.calls++
+	if len(f.errs) == 0 {
+		return nil
+	}
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return err
+}
+
+func (f *fakeWriter) InsertWebHook(ctx context.Context, webhook *model.WebHook) (*[]model.WebHook, error) {
+	return &[]model.WebHook{}, f.next()
+}
+
+func (f *fakeWriter) InsertWebHooks(ctx context.Context, webhooks []model.WebHook) (*[]model.WebHook, error) {
+	f.traceID = ctx.Value("trace-request-id")
+	return &[]model.WebHook{}, f.next()
+}
+
+func (f *fakeWriter) CountPendingWebHooks(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func newTestServer(t *testing.T, writer *fakeWriter, consumer *fakeConsumer) (*ServerWorker, *model.AppServer) {
+	t.Helper()
+	tracer = otel.Tracer("test")
+	appServer := &model.AppServer{
+		InfoPod: &model.InfoPod{PodName: "test"},
+		ConsumerConfig: &model.ConsumerConfig{DlqTopic: "topic.webhook.dlq", MaxRetry: 3, RetryDelay: 1, BatchSize: 10, BatchTimeout: 10},
+	}
+	return NewServerWorker(writer, consumer), appServer
+}
+
+func newTestMessage(partition int32, offset int64, payload string) event.Message {
+	return event.Message{Topic: "topic.webhook", Partition: partition, Offset: offset, Header: map[string]string{}, Payload: []byte(payload)}
+}
+
+func TestConsumeMessage(t *testing.T) {
+	errMessage := errors.New("message rejected")
+
+	tests := []struct {
+		name			string
+		payload			string
+		errs			[]error
+		deadLetterErr	error
+		dlqSkip			bool
+		committed		bool
+		rewound			bool
+		deadLetters		[]string
+	}{
+		{
+			name: "committed after success",
+			payload: `{}`,
+			committed: true,
+		},
+		{
+			name: "committed after a retry",
+			payload: `{}`,
+			errs: []error{errMessage},
+			committed: true,
+		},
+		{
+			name: "poison to the dead-letter without retry",
+			payload: `not json`,
+			committed: true,
+			deadLetters: []string{"POISON:1"},
+		},
+		{
+			name: "retry exhausted to the dead-letter",
+			payload: `{}`,
+			errs: []error{errMessage, errMessage, errMessage},
+			committed: true,
+			deadLetters: []string{"RETRY-EXHAUSTED:3"},
+		},
+		{
+			name: "infrastructure error rewinds without dead-letter",
+			payload: `{}`,
+			errs: []error{context.DeadlineExceeded, context.DeadlineExceeded, context.DeadlineExceeded},
+			rewound: true,
+		},
+		{
+			name: "dead-letter unavailable rewinds",
+			payload: `not json`,
+			deadLetterErr: errors.New("broker down"),
+			rewound: true,
+		},
+		{
+			name: "poison skipped without dead-letter topic",
+			payload: `not json`,
+			dlqSkip: true,
+			committed: true,
+		},
+		{
+			name: "retry exhausted skipped without dead-letter topic",
+			payload: `{}`,
+			errs: []error{errMessage, errMessage, errMessage},
+			dlqSkip: true,
+			committed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumer := &fakeConsumer{deadLetterErr: tt.deadLetterErr}
+			s, appServer := newTestServer(t, &fakeWriter{errs: tt.errs}, consumer)
+			if tt.dlqSkip {
+				appServer.ConsumerConfig.DlqTopic = ""
+				appServer.ConsumerConfig.DlqSkip = true
+				consumer.deadLetterErr = event.ErrNoDeadLetter
+			}
+			offsets := newOffsetTracker()
+			msg := newTestMessage(0, 7, tt.payload)
+
+			s.consumeMessage(context.Background(), appServer, msg, offsets)
+
+			if got := len(consumer.committed) == 1; got != tt.committed {
+				t.Fatalf("got committed %v, want %v", got, tt.committed)
+			}
+			if got := len(consumer.seeks) == 1 && consumer.seeks[0].Offset == msg.Offset; got != tt.rewound {
+				t.Fatalf("got rewound %v, want %v", got, tt.rewound)
+			}
+			if got := offsets.stale(newTestMessage(0, 8, `{}`)); got != tt.rewound {
+				t.Fatalf("got next message stale %v, want %v", got, tt.rewound)
+			}
+			if fmt.Sprint(consumer.deadLetters) != fmt.Sprint(tt.deadLetters) {
+				t.Fatalf("got dead-letters %v, want %v", consumer.deadLetters, tt.deadLetters)
+			}
+		})
+	}
+}
+
+func TestConsumeBatch(t *testing.T) {
+	batch := []event.Message{newTestMessage(0, 1, `{}`), newTestMessage(0, 2, `{}`), newTestMessage(1, 1, `{}`)}
+
+	t.Run("committed in a single commit", func(t *testing.T) {
+		consumer := &fakeConsumer{}
+		writer := &fakeWriter{}
+		s, appServer := newTestServer(t, writer, consumer)
+
+		s.consumeBatch(context.Background(), appServer, batch, newOffsetTracker())
+
+		if writer.calls != 1 || len(consumer.committed) != len(batch) {
+			t.Fatalf("got %v inserts and %v committed, want 1 and %v", writer.calls, len(consumer.committed), len(batch))
+		}
+	})
+
+	t.Run("failed batch consumed one by one, a failed message holds its partition", func(t *testing.T) {
+		consumer := &fakeConsumer{}
+		// the batch, then the 3 attempts of the first message of partition 0
+		writer := &fakeWriter{errs: []error{errors.New("batch failed"), context.DeadlineExceeded, context.DeadlineExceeded, context.DeadlineExceeded}}
+		s, appServer := newTestServer(t, writer, consumer)
+		offsets := newOffsetTracker()
+
+		s.consumeBatch(context.Background(), appServer, batch, offsets)
+
+		// partition 0 rewound to offset 1 (offset 2 not committed), partition 1 committed
+		if len(consumer.committed) != 1 || consumer.committed[0].Partition != 1 {
+			t.Fatalf("got committed %v, want only partition 1", consumer.committed)
+		}
+		if len(consumer.seeks) != 1 || consumer.seeks[0].Partition != 0 || consumer.seeks[0].Offset != 1 {
+			t.Fatalf("got seeks %v, want partition 0 offset 1", consumer.seeks)
+		}
+	})
+}
+
+func TestProcessBatchKeepsTraceIds(t *testing.T) {
+	writer := &fakeWriter{}
+	s, appServer := newTestServer(t, writer, &fakeConsumer{})
+
+	batch := []event.Message{newTestMessage(0, 1, `{}`), newTestMessage(0, 2, `{}`), newTestMessage(0, 3, `{}`)}
+	batch[0].Header["trace-request-id"] = "trace-1"
+	batch[2].Header["trace-request-id"] = "trace-3"
+
+	err := s.processBatch(context.Background(), appServer, batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if writer.traceID != "trace-1,trace-3" {
+		t.Fatalf("got trace-request-id %v, want trace-1,trace-3", writer.traceID)
+	}
+}
+
+func TestNextBatchSkipsReadAhead(t *testing.T) {
+	s, appServer := newTestServer(t, &fakeWriter{}, &fakeConsumer{})
+	offsets := newOffsetTracker()
+	offsets.rewind(newTestMessage(0, 5, `{}`))
+
+	messages := make(chan event.Message, 10)
+	for _, msg := range []event.Message{
+		newTestMessage(0, 6, `{}`),		// read ahead of the rewind, skipped
+		newTestMessage(1, 3, `{}`),		// another partition
+		newTestMessage(0, 5, `{}`),		// the rewound message is back
+		newTestMessage(0, 6, `{}`),		// read again after it
+	} {
+		messages <- msg
+	}
+	close(messages)
+
+	batch, open := s.nextBatch(messages, offsets, appServer.ConsumerConfig)
+	if open {
+		t.Fatal("got channel open, want closed")
+	}
+
+	got := []string{}
+	for _, msg := range batch {
+		got = append(got, fmt.Sprintf("%v:%v", msg.Partition, msg.Offset))
+	}
+	if fmt.Sprint(got) != "[1:3 0:5 0:6]" {
+		t.Fatalf("got batch %v, want [1:3 0:5 0:6]", got)
+	}
+}
+
+func TestRebalanceResetsRewind(t *testing.T) {
+	consumer := &fakeConsumer{}
+	offsets := newOffsetTracker()
+	consumer.OnRebalance(offsets.reset)
+
+	offsets.rewind(newTestMessage(0, 5, `{}`))
+	offsets.rewind(newTestMessage(1, 5, `{}`))
+
+	// partition 0 revoked, then assigned again after another consumer committed up to offset 9
+	consumer.rebalance("topic.webhook", 0)
+
+	if offsets.stale(newTestMessage(0, 9, `{}`)) {
+		t.Fatal("got partition 0 stale after the rebalance")
+	}
+	if !offsets.stale(newTestMessage(1, 9, `{}`)) {
+		t.Fatal("got partition 1 not stale, it was not revoked")
+	}
+}
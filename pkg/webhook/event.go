@@ -0,0 +1,60 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event is a verified webhook, Data is the body as sent
+type Event struct {
+	ID				string			`json:"id"`
+	Timestamp		time.Time		`json:"timestamp"`
+	Data			json.RawMessage	`json:"data"`
+}
+
+// the role of the receiver in a PIX transaction
+const (
+	RolePayer	= "PAYER"
+	RolePayee	= "PAYEE"
+)
+
+type StepProcess struct {
+	Name		string  	`json:"step_process,omitempty"`
+	ProcessedAt	time.Time 	`json:"processed_at,omitempty"`
+}
+
+type Account struct {
+	ID				int			`json:"id,omitempty"`
+	AccountID		string		`json:"account_id,omitempty"`
+	PersonID		string  	`json:"person_id,omitempty"`
+}
+
+type PixTransaction struct {
+	ID				int			`json:"id,omitempty"`
+	TransactionId	string 		`json:"transaction_id,omitempty"`
+	TransactionAt	time.Time 	`json:"transaction_at,omitempty"`	
+	RequestId		string 		`json:"request_id,omitempty"`
+	AccountFrom		Account		`json:"account_from,omitempty"`
+	AccountTo		Account		`json:"account_to,omitempty"`
+	Status			string  	`json:"status,omitempty"`
+	Currency		string 		`json:"currency,omitempty"`
+	Amount			float64 	`json:"amount,omitempty"`	
+	StepProcess		*[]StepProcess	`json:"step_process,omitempty"`
+	CreatedAt		time.Time 	`json:"created_at,omitempty"`
+	UpdatedAt		*time.Time 	`json:"updated_at,omitempty"`
+	Role			string		`json:"role,omitempty"`
+}
+
+// About decode the data of the event
+func (e *Event) Decode(v any) error {
+	return json.Unmarshal(e.Data, v)
+}
+
+// About decode the data of a PIX event
+func (e *Event) PixTransaction() (*PixTransaction, error) {
+	pixTransaction := PixTransaction{}
+	if err := e.Decode(&pixTransaction); err != nil {
+		return nil, err
+	}
+	return &pixTransaction, nil
+}
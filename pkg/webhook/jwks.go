@@ -0,0 +1,58 @@
+package webhook
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+)
+
+var ErrInvalidKey = errors.New("invalid webhook public key")
+
+// JWK is an ed25519 public key (RFC 8037)
+type JWK struct {
+	Kty		string	`json:"kty"`
+	Crv		string	`json:"crv"`
+	X		string	`json:"x"`
+	Kid		string	`json:"kid"`
+	Use		string	`json:"use,omitempty"`
+	Alg		string	`json:"alg,omitempty"`
+}
+
+// JWKS is the key set published by the worker
+type JWKS struct {
+	Keys	[]JWK	`json:"keys"`
+}
+
+// About convert an ed25519 public key to JWK
+func NewJWK(keyID string, publicKey ed25519.PublicKey) JWK {
+	return JWK{
+		Kty: "OKP",
+		Crv: "Ed25519",
+		X: base64.RawURLEncoding.EncodeToString(publicKey),
+		Kid: keyID,
+		Use: "sig",
+		Alg: "EdDSA",
+	}
+}
+
+// About convert a JWK to an ed25519 public key
+func (j JWK) PublicKey() (ed25519.PublicKey, error) {
+	if j.Kty != "OKP" || j.Crv != "Ed25519" {
+		return nil, ErrInvalidKey
+	}
+	x, err := base64.RawURLEncoding.DecodeString(j.X)
+	if err != nil || len(x) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey
+	}
+	return ed25519.PublicKey(x), nil
+}
+
+// About find a key by kid
+func (j JWKS) Key(keyID string) (*JWK, bool) {
+	for i := range j.Keys {
+		if j.Keys[i].Kid == keyID {
+			return &j.Keys[i], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,127 @@
+// Package webhook holds the signature scheme of the webhooks sent by go-worker-webhook.
+//
+// The scheme is compatible with Standard Webhooks (https://www.standardwebhooks.com):
+// the signed content is "<webhook-id>.<webhook-timestamp>.<body>" and the
+// webhook-signature header is a space separated list of "v1,<base64 hmac-sha256>"
+// (shared secret) and/or "v1a,<base64 ed25519>" (asymmetric, the public keys are
+// published as a JWKS and the key id is sent in the webhook-key-id header).
+//
+// The receivers verify the webhooks with a Verifier and decode the body
+// from the returned Event.
+package webhook
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderID			= "webhook-id"
+	HeaderTimestamp		= "webhook-timestamp"
+	HeaderSignature		= "webhook-signature"
+	HeaderKeyID			= "webhook-key-id"
+	HeaderSequence		= "webhook-sequence"
+
+	SecretPrefix		= "whsec_"
+	VersionHMAC			= "v1"
+	VersionEd25519		= "v1a"
+)
+
+var ErrInvalidSecret = errors.New("invalid webhook secret")
+
+// About decode a secret in the whsec_<base64> format (the prefix is optional)
+func DecodeSecret(secret string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, SecretPrefix))
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+	return key, nil
+}
+
+// About build the signed content
+func SignedContent(id string, timestamp time.Time, body []byte) []byte {
+	content := make([]byte, 0, len(id) + len(body) + 32)
+	content = append(content, id...)
+	content = append(content, '.')
+	content = strconv.AppendInt(content, timestamp.Unix(), 10)
+	content = append(content, '.')
+	content = append(content, body...)
+	return content
+}
+
+// About hmac-sha256 of the signed content
+func hmacSum(key []byte, content []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(content)
+	return mac.Sum(nil)
+}
+
+// About sign with hmac-sha256, the result is the "v1,<base64>" signature
+func SignHMAC(key []byte, id string, timestamp time.Time, body []byte) string {
+	return VersionHMAC + "," + base64.StdEncoding.EncodeToString(hmacSum(key, SignedContent(id, timestamp, body)))
+}
+
+// About sign with ed25519, the result is the "v1a,<base64>" signature
+func SignEd25519(privateKey ed25519.PrivateKey, id string, timestamp time.Time, body []byte) string {
+	return VersionEd25519 + "," + base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, SignedContent(id, timestamp, body)))
+}
+
+// Signer signs a webhook with one or more secrets, during a secret rotation
+// the webhook is signed with both the new and the old secret
+type Signer struct {
+	keys		[][]byte
+	keyIDs		[]string
+	privateKeys	[]ed25519.PrivateKey
+}
+
+// About create a signer, the secrets are in the whsec_<base64> format
+func NewSigner(secrets ...string) (*Signer, error) {
+	signer := Signer{}
+	for _, secret := range secrets {
+		key, err := DecodeSecret(secret)
+		if err != nil {
+			return nil, err
+		}
+		signer.keys = append(signer.keys, key)
+	}
+	return &signer, nil
+}
+
+// About add an ed25519 private key, the key id is the kid of its public key in the JWKS
+func (s *Signer) AddEd25519(keyID string, privateKey ed25519.PrivateKey) {
+	s.keyIDs = append(s.keyIDs, keyID)
+	s.privateKeys = append(s.privateKeys, privateKey)
+}
+
+// About sign a webhook, the result is the value of the webhook-signature header
+func (s *Signer) Sign(id string, timestamp time.Time, body []byte) string {
+	signatures := make([]string, 0, len(s.keys) + len(s.privateKeys))
+	for _, key := range s.keys {
+		signatures = append(signatures, SignHMAC(key, id, timestamp, body))
+	}
+	for _, privateKey := range s.privateKeys {
+		signatures = append(signatures, SignEd25519(privateKey, id, timestamp, body))
+	}
+	return strings.Join(signatures, " ")
+}
+
+// About the headers of a signed webhook
+func (s *Signer) Headers(id string, timestamp time.Time, body []byte) map[string]string {
+	headers := map[string]string{
+		HeaderID: id,
+		HeaderTimestamp: strconv.FormatInt(timestamp.Unix(), 10),
+	}
+	if len(s.keys) > 0 || len(s.privateKeys) > 0 {
+		headers[HeaderSignature] = s.Sign(id, timestamp, body)
+	}
+	if len(s.keyIDs) > 0 {
+		headers[HeaderKeyID] = strings.Join(s.keyIDs, " ")
+	}
+	return headers
+}
@@ -0,0 +1,155 @@
+package webhook
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTolerance is the max difference between the webhook-timestamp and the
+// receiver clock, older (or newer) webhooks are rejected to prevent replays
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	ErrMissingHeaders		= errors.New("missing webhook headers")
+	ErrInvalidTimestamp		= errors.New("invalid webhook timestamp")
+	ErrTimestampTooOld		= errors.New("webhook timestamp too old")
+	ErrTimestampTooNew		= errors.New("webhook timestamp too new")
+	ErrInvalidSignature		= errors.New("no matching webhook signature")
+)
+
+// Verifier verifies the signature of the webhooks sent by go-worker-webhook,
+// with the shared secrets (v1) and/or the published public keys (v1a)
+type Verifier struct {
+	keys		[][]byte
+	publicKeys	map[string]ed25519.PublicKey
+	tolerance	time.Duration
+	now			func() time.Time
+}
+
+// About create a verifier for the hmac signature, the secrets are in the whsec_<base64> format
+// (more than one secret can be used during a secret rotation)
+func NewVerifier(secrets ...string) (*Verifier, error) {
+	verifier := Verifier{
+		publicKeys: map[string]ed25519.PublicKey{},
+		tolerance: DefaultTolerance,
+		now: time.Now,
+	}
+	for _, secret := range secrets {
+		key, err := DecodeSecret(secret)
+		if err != nil {
+			return nil, err
+		}
+		verifier.keys = append(verifier.keys, key)
+	}
+	return &verifier, nil
+}
+
+// About create a verifier for the ed25519 signature with the JWKS published by the worker
+func NewJWKSVerifier(jwks JWKS) (*Verifier, error) {
+	verifier, _ := NewVerifier()
+	for _, jwk := range jwks.Keys {
+		publicKey, err := jwk.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+		verifier.publicKeys[jwk.Kid] = publicKey
+	}
+	return verifier, nil
+}
+
+// About set the timestamp tolerance
+func (v *Verifier) WithTolerance(tolerance time.Duration) *Verifier {
+	v.tolerance = tolerance
+	return v
+}
+
+// About verify the headers and the body as received (the body must not be decoded before)
+func (v *Verifier) Verify(header http.Header, body []byte) error {
+	_, err := v.Parse(header, body)
+	return err
+}
+
+// About verify and return the event
+func (v *Verifier) Parse(header http.Header, body []byte) (*Event, error) {
+	id := header.Get(HeaderID)
+	timestampHeader := header.Get(HeaderTimestamp)
+	signatureHeader := header.Get(HeaderSignature)
+	if id == "" || timestampHeader == "" || signatureHeader == "" {
+		return nil, ErrMissingHeaders
+	}
+
+	unix, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidTimestamp
+	}
+	timestamp := time.Unix(unix, 0)
+
+	now := v.now()
+	if now.Sub(timestamp) > v.tolerance {
+		return nil, ErrTimestampTooOld
+	}
+	if timestamp.Sub(now) > v.tolerance {
+		return nil, ErrTimestampTooNew
+	}
+
+	content := SignedContent(id, timestamp, body)
+	keyIDs := strings.Fields(header.Get(HeaderKeyID))
+
+	for _, versionedSignature := range strings.Fields(signatureHeader) {
+		version, signature, found := strings.Cut(versionedSignature, ",")
+		if !found {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(signature)
+		if err != nil {
+			continue
+		}
+		switch version {
+		case VersionHMAC:
+			if v.verifyHMAC(content, decoded) {
+				return &Event{ID: id, Timestamp: timestamp, Data: body}, nil
+			}
+		case VersionEd25519:
+			if v.verifyEd25519(keyIDs, content, decoded) {
+				return &Event{ID: id, Timestamp: timestamp, Data: body}, nil
+			}
+		}
+	}
+
+	return nil, ErrInvalidSignature
+}
+
+// About check the signature against every secret
+func (v *Verifier) verifyHMAC(content []byte, signature []byte) bool {
+	for _, key := range v.keys {
+		if hmac.Equal(hmacSum(key, content), signature) {
+			return true
+		}
+	}
+	return false
+}
+
+// About check the signature against the keys of the webhook-key-id header (every key when not sent)
+func (v *Verifier) verifyEd25519(keyIDs []string, content []byte, signature []byte) bool {
+	if len(keyIDs) == 0 {
+		for _, publicKey := range v.publicKeys {
+			if ed25519.Verify(publicKey, content, signature) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, keyID := range keyIDs {
+		publicKey, ok := v.publicKeys[keyID]
+		if ok && ed25519.Verify(publicKey, content, signature) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package webhook
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func newSecret(t *testing.T) string {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return SecretPrefix + base64.StdEncoding.EncodeToString(key)
+}
+
+func newKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return publicKey, privateKey
+}
+
+func toHeader(headers map[string]string) http.Header {
+	header := http.Header{}
+	for key, value := range headers {
+		header.Set(key, value)
+	}
+	return header
+}
+
+func TestVerify(t *testing.T) {
+	secret, oldSecret, otherSecret := newSecret(t), newSecret(t), newSecret(t)
+	publicKey, privateKey := newKey(t)
+	otherPublicKey, _ := newKey(t)
+
+	now := time.Unix(1760000000, 0)
+	body := []byte(`{"transactionId":"TX-001","amount":10.5}`)
+
+	hmacSigner, _ := NewSigner(secret)
+	rotationSigner, _ := NewSigner(secret, oldSecret)
+	ed25519Signer, _ := NewSigner()
+	ed25519Signer.AddEd25519("kid-2", privateKey)
+
+	jwks := JWKS{Keys: []JWK{NewJWK("kid-1", otherPublicKey), NewJWK("kid-2", publicKey)}}
+
+	tests := []struct {
+		name		string
+		signer		*Signer
+		verifier	func() (*Verifier, error)
+		timestamp	time.Time
+		body		[]byte
+		edit		func(http.Header)
+		err			error
+	}{
+		{
+			name: "hmac v1",
+			signer: hmacSigner,
+			verifier: func() (*Verifier, error) { return NewVerifier(secret) },
+			timestamp: now,
+			body: body,
+		},
+		{
+			name: "hmac v1 with another secret",
+			signer: hmacSigner,
+			verifier: func() (*Verifier, error) { return NewVerifier(otherSecret) },
+			timestamp: now,
+			body: body,
+			err: ErrInvalidSignature,
+		},
+		{
+			name: "tampered body",
+			signer: hmacSigner,
+			verifier: func() (*Verifier, error) { return NewVerifier(secret) },
+			timestamp: now,
+			body: []byte(`{"transactionId":"TX-001","amount":99.5}`),
+			err: ErrInvalidSignature,
+		},
+		{
+			name: "timestamp too old",
+			signer: hmacSigner,
+			verifier: func() (*Verifier, error) { return NewVerifier(secret) },
+			timestamp: now.Add(-DefaultTolerance - time.Second),
+			body: body,
+			err: ErrTimestampTooOld,
+		},
+		{
+			name: "timestamp too new",
+			signer: hmacSigner,
+			verifier: func() (*Verifier, error) { return NewVerifier(secret) },
+			timestamp: now.Add(DefaultTolerance + time.Second),
+			body: body,
+			err: ErrTimestampTooNew,
+		},
+		{
+			name: "missing signature",
+			signer: hmacSigner,
+			verifier: func() (*Verifier, error) { return NewVerifier(secret) },
+			timestamp: now,
+			body: body,
+			edit: func(header http.Header) { header.Del(HeaderSignature) },
+			err: ErrMissingHeaders,
+		},
+		{
+			name: "rotation, receiver with the new secret",
+			signer: rotationSigner,
+			verifier: func() (*Verifier, error) { return NewVerifier(secret) },
+			timestamp: now,
+			body: body,
+		},
+		{
+			name: "rotation, receiver still with the old secret",
+			signer: rotationSigner,
+			verifier: func() (*Verifier, error) { return NewVerifier(oldSecret) },
+			timestamp: now,
+			body: body,
+		},
+		{
+			name: "ed25519 v1a by kid",
+			signer: ed25519Signer,
+			verifier: func() (*Verifier, error) { return NewJWKSVerifier(jwks) },
+			timestamp: now,
+			body: body,
+		},
+		{
+			name: "ed25519 v1a with the kid of another key",
+			signer: ed25519Signer,
+			verifier: func() (*Verifier, error) { return NewJWKSVerifier(jwks) },
+			timestamp: now,
+			body: body,
+			edit: func(header http.Header) { header.Set(HeaderKeyID, "kid-1") },
+			err: ErrInvalidSignature,
+		},
+		{
+			name: "ed25519 v1a without kid",
+			signer: ed25519Signer,
+			verifier: func() (*Verifier, error) { return NewJWKSVerifier(jwks) },
+			timestamp: now,
+			body: body,
+			edit: func(header http.Header) { header.Del(HeaderKeyID) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := toHeader(tt.signer.Headers("msg_1", tt.timestamp, body))
+			if tt.edit != nil {
+				tt.edit(header)
+			}
+
+			verifier, err := tt.verifier()
+			if err != nil {
+				t.Fatal(err)
+			}
+			verifier.now = func() time.Time { return now }
+
+			event, err := verifier.Parse(header, tt.body)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if err == nil && (event.ID != "msg_1" || !event.Timestamp.Equal(tt.timestamp)) {
+				t.Fatalf("got event %+v", event)
+			}
+		})
+	}
+}
+
+func TestJWKSKey(t *testing.T) {
+	publicKey, _ := newKey(t)
+	jwks := JWKS{Keys: []JWK{NewJWK("kid-1", publicKey)}}
+
+	jwk, ok := jwks.Key("kid-1")
+	if !ok {
+		t.Fatal("kid-1 not found")
+	}
+	res_key, err := jwk.PublicKey()
+	if err != nil || !res_key.Equal(publicKey) {
+		t.Fatalf("got key %v, error %v", res_key, err)
+	}
+
+	if _, ok := jwks.Key("kid-2"); ok {
+		t.Fatal("kid-2 found")
+	}
+
+	invalid := JWK{Kty: "RSA", Crv: "Ed25519", X: jwk.X, Kid: "kid-3"}
+	if _, err := invalid.PublicKey(); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("got error %v, want %v", err, ErrInvalidKey)
+	}
+}